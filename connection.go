@@ -0,0 +1,287 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/ini.v1"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+)
+
+// ConnectionSettings contains settings for DB connection
+type ConnectionSettings struct {
+	driver        string
+	user          string
+	password      string
+	dbname        string
+	dbhost        string
+	port          string
+	socket        string
+	tlsCA         string
+	params        string
+	dsn           string
+	ssh           string
+	sshKey        string
+	sshKnownHosts string
+}
+
+// ConnectionOptions contains options of command line which define where settings of connection are read from
+type ConnectionOptions struct {
+	configFile    string
+	profile       string
+	dsn           string
+	askPassword   bool
+	ssh           string
+	sshKey        string
+	sshKnownHosts string
+}
+
+// connect reads connection settings and connects to DB
+func connect(dbConnect dbConnector, connOptions *ConnectionOptions) (*sql.DB, error) {
+	conset, err := getConnectionSettings(connOptions)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Connection settings are read", "driver", conset.driver, "host", conset.dbhost, "user", conset.user, "database", conset.dbname, "ssh", conset.ssh)
+	return dbConnect(conset)
+}
+
+// connectionKeys are names of settings of connection in environment and config file
+var connectionKeys = []string{"DB_USER", "DB_PASSWORD", "DB_PASSWORD_FILE", "DB_NAME", "DB_HOST", "DB_PORT", "DB_SOCKET", "DB_TLS_CA", "DB_PARAMS", "DB_DSN",
+	"DB_SSH", "DB_SSH_KEY", "DB_SSH_KNOWN_HOSTS"}
+
+// getConnectionSettings reads settings from DSN of command line, environment or config file.
+// DSN contains all settings of connection, only DB_TLS_CA, SSH tunnel and sources of password are applied to it.
+func getConnectionSettings(connOptions *ConnectionOptions) (*ConnectionSettings, error) {
+	values, err := readConnectionValues(connOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if dsn := values["DB_DSN"]; dsn != "" {
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to parse DSN: %s", err)
+		}
+		conset := &ConnectionSettings{
+			driver:        "mysql",
+			user:          cfg.User,
+			password:      cfg.Passwd,
+			dbname:        cfg.DBName,
+			dbhost:        cfg.Addr,
+			tlsCA:         values["DB_TLS_CA"],
+			dsn:           dsn,
+			ssh:           values["DB_SSH"],
+			sshKey:        values["DB_SSH_KEY"],
+			sshKnownHosts: values["DB_SSH_KNOWN_HOSTS"],
+		}
+		return conset, conset.applyCredentials(values["DB_PASSWORD_FILE"], connOptions.askPassword)
+	}
+
+	conset := &ConnectionSettings{
+		driver:        "mysql",
+		user:          values["DB_USER"],
+		password:      values["DB_PASSWORD"],
+		dbname:        values["DB_NAME"],
+		dbhost:        values["DB_HOST"],
+		port:          values["DB_PORT"],
+		socket:        values["DB_SOCKET"],
+		tlsCA:         values["DB_TLS_CA"],
+		params:        values["DB_PARAMS"],
+		ssh:           values["DB_SSH"],
+		sshKey:        values["DB_SSH_KEY"],
+		sshKnownHosts: values["DB_SSH_KNOWN_HOSTS"],
+	}
+	return conset, conset.applyCredentials(values["DB_PASSWORD_FILE"], connOptions.askPassword)
+}
+
+// readConnectionValues merges settings of connection by precedence: options --dsn and --ssh*,
+// environment variables, section of profile in config file, unnamed section of config file.
+// Environment is ignored when profile is selected, so settings of profile are not mixed with settings of another DB.
+// Config file is optional when environment defines DB_NAME or DB_DSN and profile is not selected.
+func readConnectionValues(connOptions *ConnectionOptions) (map[string]string, error) {
+	fileValues, err := readConfigFile(connOptions.configFile, connOptions.profile)
+	if err != nil {
+		if connOptions.profile != "" {
+			return nil, err
+		}
+		if connOptions.dsn == "" && os.Getenv("DB_DSN") == "" && os.Getenv("DB_NAME") == "" {
+			return nil, fmt.Errorf("Empty DB_NAME and DB_DSN in environment and fail to read config file: %v", err)
+		}
+	}
+
+	values := make(map[string]string, len(connectionKeys))
+	for _, key := range connectionKeys {
+		values[key] = fileValues[key]
+		if value := os.Getenv(key); value != "" && connOptions.profile == "" {
+			values[key] = value
+		}
+	}
+	flagValues := map[string]string{
+		"DB_DSN":             connOptions.dsn,
+		"DB_SSH":             connOptions.ssh,
+		"DB_SSH_KEY":         connOptions.sshKey,
+		"DB_SSH_KNOWN_HOSTS": connOptions.sshKnownHosts,
+	}
+	for key, value := range flagValues {
+		if value != "" {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// readConfigFile returns settings of connection from config file.
+// Section of profile inherits values of unnamed section.
+func readConfigFile(configFile string, profile string) (map[string]string, error) {
+	cfg, err := ini.Load(configFile)
+	if err != nil {
+		return nil, err
+	}
+	sections := []*ini.Section{cfg.Section("")}
+	if profile != "" {
+		section, err := cfg.GetSection(profile)
+		if err != nil {
+			return nil, fmt.Errorf("Profile '%s' is not found in config file %s", profile, configFile)
+		}
+		sections = append(sections, section)
+	}
+
+	values := make(map[string]string)
+	for _, section := range sections {
+		for _, key := range connectionKeys {
+			if section.HasKey(key) {
+				values[key] = section.Key(key).String()
+			}
+		}
+	}
+	return values, nil
+}
+
+// tunnelConnector is connector of DB which closes SSH tunnel when DB is closed
+type tunnelConnector struct {
+	driver.Connector
+	tunnel *sshTunnel
+}
+
+// Close is called by sql.DB.Close
+func (connector *tunnelConnector) Close() error {
+	return connector.tunnel.Close()
+}
+
+// newConnector returns connector of MySQL driver. Connections go through SSH tunnel if DB_SSH is defined.
+func (conset *ConnectionSettings) newConnector() (driver.Connector, error) {
+	cfg, err := conset.mysqlConfig()
+	if err != nil {
+		return nil, err
+	}
+	if conset.ssh == "" {
+		return mysql.NewConnector(cfg)
+	}
+
+	remoteNet, remoteAddr := cfg.Net, cfg.Addr
+	if remoteNet == "tcp" {
+		remoteAddr = ensurePort(remoteAddr)
+		pinServerName(cfg, remoteAddr)
+	}
+	tunnel, err := conset.openSSHTunnel(remoteNet, remoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Net = "tcp"
+	cfg.Addr = tunnel.localAddr()
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	return &tunnelConnector{connector, tunnel}, nil
+}
+
+// ensurePort adds default port of MySQL to address of DB, empty address means DB on SSH server
+func ensurePort(addr string) string {
+	if addr == "" {
+		return net.JoinHostPort("127.0.0.1", defaultPort)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return net.JoinHostPort(strings.Trim(addr, "[]"), defaultPort)
+	}
+	return addr
+}
+
+// pinServerName keeps verification of certificate of DB host when address is replaced by local port of tunnel
+func pinServerName(cfg *mysql.Config, addr string) {
+	if cfg.TLS == nil && cfg.TLSConfig == "true" {
+		cfg.TLS = &tls.Config{}
+	}
+	if cfg.TLS == nil || cfg.TLS.ServerName != "" || cfg.TLS.InsecureSkipVerify {
+		return
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return
+	}
+	cfg.TLS = cfg.TLS.Clone()
+	cfg.TLS.ServerName = host
+}
+
+// mysqlConfig builds config of MySQL driver, so values are not escaped manually.
+// DB_SOCKET is used instead of DB_HOST and DB_PORT.
+func (conset *ConnectionSettings) mysqlConfig() (cfg *mysql.Config, err error) {
+	if conset.dsn != "" {
+		cfg, err = mysql.ParseDSN(conset.dsn)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to parse DSN: %s", err)
+		}
+		if conset.password != "" {
+			cfg.Passwd = conset.password
+		}
+		return cfg, conset.applyTLSCA(cfg)
+	}
+
+	cfg = mysql.NewConfig()
+	if conset.params != "" {
+		cfg, err = mysql.ParseDSN("/?" + conset.params)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to parse DB_PARAMS: %s", err)
+		}
+		// TLS is built again from parameter tls for the real address of server
+		cfg.TLS = nil
+	}
+	cfg.User = conset.user
+	cfg.Passwd = conset.password
+	cfg.DBName = conset.dbname
+	cfg.Net = "tcp"
+	cfg.Addr = conset.dbhost
+	if conset.port != "" {
+		cfg.Addr = net.JoinHostPort(conset.dbhost, conset.port)
+	}
+	if conset.socket != "" {
+		cfg.Net = "unix"
+		cfg.Addr = conset.socket
+	}
+	return cfg, conset.applyTLSCA(cfg)
+}
+
+// applyTLSCA enables TLS with verification of certificate of server by CA from file DB_TLS_CA
+func (conset *ConnectionSettings) applyTLSCA(cfg *mysql.Config) error {
+	if conset.tlsCA == "" {
+		return nil
+	}
+	pem, err := ioutil.ReadFile(conset.tlsCA)
+	if err != nil {
+		return fmt.Errorf("Fail to read CA certificate: %s", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("Fail to read CA certificate: no certificates in PEM file %s", conset.tlsCA)
+	}
+	cfg.TLS = &tls.Config{RootCAs: rootCAs}
+	return nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetConnectionSettingsFileError(t *testing.T) {
+	os.Setenv("DB_NAME", "")
+	_, err := getConnectionSettings(&ConnectionOptions{configFile: "not_existing_file"})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestGetConnectionSettings(t *testing.T) {
+	os.Setenv("DB_NAME", "")
+	conset, err := getConnectionSettings(testConnection)
+	os.Setenv("DB_NAME", "")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	fields := map[string]string{
+		"driver":   "mysql",
+		"user":     "root",
+		"password": "root",
+		"dbname":   "test",
+		"dbhost":   "127.0.0.1",
+		"port":     "",
+		"socket":   "",
+		"dsn":      "",
+	}
+	for key, val := range fields {
+		r := reflect.ValueOf(conset)
+		f := reflect.Indirect(r).FieldByName(key)
+		actualValue := f.String()
+		if actualValue != val {
+			t.Errorf("Expected for %s: %s, got: %s", key, val, actualValue)
+			return
+		}
+	}
+}
+
+func TestGetConnectionSettingsWithDSN(t *testing.T) {
+	conset, err := getConnectionSettings(&ConnectionOptions{configFile: "not_existing_file", dsn: "user:p@ss@tcp(db:3307)/shop?parseTime=true"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "user", conset.user)
+	assert.Equal(t, "p@ss", conset.password)
+	assert.Equal(t, "db:3307", conset.dbhost)
+	assert.Equal(t, "shop", conset.dbname)
+
+	_, err = getConnectionSettings(&ConnectionOptions{dsn: "user@tcp(db"})
+	if err == nil {
+		t.Errorf("Expected error of invalid DSN, but got nil")
+	}
+}
+
+func TestGetConnectionSettingsWithProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiles")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "db.ini")
+	config := "DB_USER=reader\nDB_HOST=127.0.0.1\nDB_NAME=test\n\n" +
+		"[prod]\nDB_HOST=prod.example.com\nDB_NAME=shop\n\n" +
+		"[staging]\nDB_DSN=stage:pass@tcp(staging:3306)/shop_staging\n"
+	ioutil.WriteFile(configFile, []byte(config), 0600)
+	os.Setenv("DB_NAME", "")
+	os.Setenv("DB_USER", "")
+
+	conset, err := getConnectionSettings(&ConnectionOptions{configFile: configFile, profile: "prod"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "reader", conset.user)
+	assert.Equal(t, "prod.example.com", conset.dbhost)
+	assert.Equal(t, "shop", conset.dbname)
+
+	conset, err = getConnectionSettings(&ConnectionOptions{configFile: configFile, profile: "staging"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "stage", conset.user)
+	assert.Equal(t, "staging:3306", conset.dbhost)
+
+	conset, err = getConnectionSettings(&ConnectionOptions{configFile: configFile})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "127.0.0.1", conset.dbhost)
+	assert.Equal(t, "test", conset.dbname)
+
+	os.Setenv("DB_USER", "admin")
+	os.Setenv("DB_NAME", "local_db")
+	os.Setenv("DB_DSN", "local:pass@tcp(localhost:3306)/local_db")
+	conset, err = getConnectionSettings(&ConnectionOptions{configFile: configFile, profile: "prod"})
+	os.Setenv("DB_USER", "")
+	os.Setenv("DB_NAME", "")
+	os.Setenv("DB_DSN", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "reader", conset.user)
+	assert.Equal(t, "shop", conset.dbname)
+	assert.Equal(t, "prod.example.com", conset.dbhost)
+	assert.Equal(t, "", os.Getenv("DB_HOST"))
+
+	os.Setenv("DB_USER", "admin")
+	conset, err = getConnectionSettings(&ConnectionOptions{configFile: configFile})
+	os.Setenv("DB_USER", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "admin", conset.user)
+	assert.Equal(t, "test", conset.dbname)
+
+	conset, err = getConnectionSettings(&ConnectionOptions{configFile: configFile, profile: "prod", dsn: "cli@tcp(cli:3306)/cli_db"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "cli", conset.user)
+	assert.Equal(t, "cli_db", conset.dbname)
+
+	_, err = getConnectionSettings(&ConnectionOptions{configFile: configFile, profile: "local"})
+	assert.EqualError(t, err, fmt.Sprintf("Profile 'local' is not found in config file %s", configFile))
+
+	os.Setenv("DB_NAME", "test")
+	_, err = getConnectionSettings(&ConnectionOptions{configFile: filepath.Join(dir, "not_existing.ini"), profile: "prod"})
+	os.Setenv("DB_NAME", "")
+	if err == nil {
+		t.Errorf("Expected error of missing config file with profile, but got nil")
+	}
+}
+
+func TestGetConnectionSettingsWithSSH(t *testing.T) {
+	os.Setenv("DB_SSH", "deploy@bastion")
+	os.Setenv("DB_SSH_KEY", "~/.ssh/deploy")
+	defer os.Setenv("DB_SSH", "")
+	defer os.Setenv("DB_SSH_KEY", "")
+
+	conset, err := getConnectionSettings(&ConnectionOptions{configFile: "not_existing_file", dsn: "user:pass@tcp(db:3306)/shop", ssh: "admin@jump:2222"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "admin@jump:2222", conset.ssh)
+	assert.Equal(t, "~/.ssh/deploy", conset.sshKey)
+}
+
+func TestConSetMysqlConfig(t *testing.T) {
+	conset := &ConnectionSettings{
+		driver:   "mysql",
+		user:     "user",
+		password: "pass",
+		dbname:   "dbname",
+		dbhost:   "host",
+	}
+	cfg, err := conset.mysqlConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "user:pass@tcp(host)/dbname"
+	if dsn := cfg.FormatDSN(); dsn != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, dsn)
+	}
+
+	conset.password = "p@ss:w/rd?&"
+	conset.port = "3307"
+	cfg, err = conset.mysqlConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "host:3307", cfg.Addr)
+	parsed, err := mysql.ParseDSN(cfg.FormatDSN())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "p@ss:w/rd?&", parsed.Passwd)
+
+	conset.socket = "/var/run/mysqld/mysqld.sock"
+	cfg, _ = conset.mysqlConfig()
+	assert.Equal(t, "unix", cfg.Net)
+	assert.Equal(t, "/var/run/mysqld/mysqld.sock", cfg.Addr)
+}
+
+func TestConSetMysqlConfigParams(t *testing.T) {
+	conset := &ConnectionSettings{
+		driver: "mysql",
+		user:   "user",
+		dbname: "dbname",
+		dbhost: "host",
+		params: "charset=utf8mb4&parseTime=true&loc=Local&tls=true",
+	}
+	cfg, err := conset.mysqlConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.True(t, cfg.ParseTime)
+	assert.Equal(t, time.Local, cfg.Loc)
+	assert.Equal(t, "true", cfg.TLSConfig)
+	assert.Nil(t, cfg.TLS)
+	assert.Equal(t, "host", cfg.Addr)
+	assert.True(t, strings.Contains(cfg.FormatDSN(), "charset=utf8mb4"))
+
+	conset.params = "parseTime=maybe"
+	_, err = conset.mysqlConfig()
+	if err == nil {
+		t.Errorf("Expected error of invalid params, but got nil")
+	}
+}
+
+func TestConSetMysqlConfigDSN(t *testing.T) {
+	conset := &ConnectionSettings{driver: "mysql", dsn: "user:pass@unix(/tmp/mysql.sock)/shop?charset=utf8mb4"}
+	cfg, err := conset.mysqlConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "unix", cfg.Net)
+	assert.Equal(t, "shop", cfg.DBName)
+}
+
+func TestConSetMysqlConfigTLSCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	conset := &ConnectionSettings{driver: "mysql", dbhost: "host", tlsCA: filepath.Join(dir, "not_existing.pem")}
+	_, err = conset.mysqlConfig()
+	if err == nil {
+		t.Errorf("Expected error of missing CA file, but got nil")
+	}
+
+	conset.tlsCA = filepath.Join(dir, "empty.pem")
+	ioutil.WriteFile(conset.tlsCA, []byte("not a certificate"), 0600)
+	_, err = conset.mysqlConfig()
+	if err == nil {
+		t.Errorf("Expected error of invalid CA file, but got nil")
+	}
+
+	conset.tlsCA = filepath.Join(dir, "ca.pem")
+	writeTestCertificate(t, conset.tlsCA)
+	cfg, err := conset.mysqlConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if cfg.TLS == nil || cfg.TLS.RootCAs == nil {
+		t.Errorf("Expected TLS config with root CAs, but got %v", cfg.TLS)
+	}
+}
+
+// writeTestCertificate writes self-signed certificate in PEM format
+func writeTestCertificate(t *testing.T, filename string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err = ioutil.WriteFile(filename, contents, 0600); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/term"
+	"gopkg.in/ini.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultPort is port of MySQL which is used to find password in pgpass file when DB_PORT is not defined
+const defaultPort = "3306"
+
+// userHomeDir returns home directory with files ~/.my.cnf and ~/.pgpass, it is replaced in tests
+var userHomeDir = os.UserHomeDir
+
+// readPassword asks password in terminal without echo, it is replaced in tests
+var readPassword = func(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+// applyCredentials completes settings with credentials which are not defined by DSN, environment or config file.
+// Password is asked in terminal if askPassword is set, otherwise it is read from the first source which has it:
+// DB_PASSWORD_FILE, ~/.pgpass, section [client] of ~/.my.cnf.
+func (conset *ConnectionSettings) applyCredentials(passwordFile string, askPassword bool) error {
+	var myCnf *ini.Section
+	if home, err := userHomeDir(); err == nil {
+		myCnf = readMyCnfClient(filepath.Join(home, ".my.cnf"))
+	}
+	if myCnf != nil && conset.dsn == "" {
+		if conset.user == "" {
+			conset.user = myCnfValue(myCnf, "user")
+		}
+		if conset.dbhost == "" && conset.socket == "" {
+			conset.dbhost = myCnfValue(myCnf, "host")
+			conset.port = myCnfValue(myCnf, "port")
+			conset.socket = myCnfValue(myCnf, "socket")
+		}
+	}
+
+	if askPassword {
+		password, err := readPassword(fmt.Sprintf("Password for %s@%s: ", conset.user, conset.dbname))
+		if err != nil {
+			return fmt.Errorf("Fail to read password from terminal: %s", err)
+		}
+		conset.password = password
+		return nil
+	}
+	if conset.password != "" {
+		return nil
+	}
+
+	if passwordFile != "" {
+		password, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return fmt.Errorf("Fail to read DB_PASSWORD_FILE: %s", err)
+		}
+		conset.password = strings.TrimRight(string(password), "\r\n")
+		logger.Debug("Password is read from file", "file", passwordFile)
+		return nil
+	}
+
+	if password, ok := conset.findPgpassPassword(pgpassFile()); ok {
+		conset.password = password
+		return nil
+	}
+
+	if myCnf != nil {
+		conset.password = myCnfValue(myCnf, "password")
+	}
+	return nil
+}
+
+// readMyCnfClient returns section [client] of MySQL option file or nil if file can't be read
+func readMyCnfClient(filename string) *ini.Section {
+	cfg, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true, SkipUnrecognizableLines: true, IgnoreInlineComment: true, PreserveSurroundedQuote: true}, filename)
+	if err != nil {
+		return nil
+	}
+	section, err := cfg.GetSection("client")
+	if err != nil {
+		return nil
+	}
+	logger.Debug("Credentials are read from MySQL option file", "file", filename)
+	return section
+}
+
+// myCnfValue returns value of MySQL option file without quotes or comment after #
+func myCnfValue(section *ini.Section, key string) string {
+	value := strings.TrimSpace(section.Key(key).String())
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if i := strings.IndexByte(value, '#'); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// pgpassFile returns path of pgpass file from environment variable PGPASSFILE or ~/.pgpass
+func pgpassFile() string {
+	if filename := os.Getenv("PGPASSFILE"); filename != "" {
+		return filename
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".pgpass")
+}
+
+// findPgpassPassword finds password in lines of pgpass file in format hostname:port:database:username:password.
+// File is ignored if it is accessible by group or others like it is done by libpq.
+func (conset *ConnectionSettings) findPgpassPassword(filename string) (string, bool) {
+	if filename == "" {
+		return "", false
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return "", false
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		logger.Warn("Pgpass file is ignored, because it is accessible by group or others", "file", filename)
+		return "", false
+	}
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", false
+	}
+
+	host := conset.dbhost
+	if host == "" || conset.socket != "" {
+		host = "localhost"
+	}
+	port := conset.port
+	if port == "" {
+		port = defaultPort
+	}
+	expected := []string{host, port, conset.dbname, conset.user}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPgpassLine(line)
+		if len(fields) != 5 || !matchPgpassFields(fields[:4], expected) {
+			continue
+		}
+		logger.Debug("Password is read from pgpass file", "file", filename)
+		return fields[4], true
+	}
+	return "", false
+}
+
+// splitPgpassLine splits line of pgpass file by colons, colon and backslash in values are escaped by backslash
+func splitPgpassLine(line string) []string {
+	fields := make([]string, 0, 5)
+	var field strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case line[i] == ':':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(line[i])
+		}
+	}
+	return append(fields, field.String())
+}
+
+func matchPgpassFields(fields []string, expected []string) bool {
+	for i, field := range fields {
+		if field != "*" && field != expected[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withHomeDir replaces home directory of user with temporary directory
+func withHomeDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "home")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	userHomeDir = func() (string, error) {
+		return dir, nil
+	}
+	return dir, func() {
+		userHomeDir = os.UserHomeDir
+		os.RemoveAll(dir)
+	}
+}
+
+func TestApplyCredentialsMyCnf(t *testing.T) {
+	home, cleanup := withHomeDir(t)
+	defer cleanup()
+	myCnf := "[mysql]\nuser=other\n\n[client]\nuser=reader\npassword=\"p#ss;w\"\nhost=db.local\nport=3307 # custom port\nskip-ssl\n"
+	ioutil.WriteFile(filepath.Join(home, ".my.cnf"), []byte(myCnf), 0600)
+
+	conset := &ConnectionSettings{driver: "mysql", dbname: "shop"}
+	if err := conset.applyCredentials("", false); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "reader", conset.user)
+	assert.Equal(t, "p#ss;w", conset.password)
+	assert.Equal(t, "db.local", conset.dbhost)
+	assert.Equal(t, "3307", conset.port)
+
+	conset = &ConnectionSettings{driver: "mysql", user: "admin", password: "secret", dbname: "shop", socket: "/tmp/mysql.sock"}
+	if err := conset.applyCredentials("", false); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "admin", conset.user)
+	assert.Equal(t, "secret", conset.password)
+	assert.Equal(t, "", conset.dbhost)
+	assert.Equal(t, "/tmp/mysql.sock", conset.socket)
+}
+
+func TestApplyCredentialsPasswordFile(t *testing.T) {
+	home, cleanup := withHomeDir(t)
+	defer cleanup()
+	ioutil.WriteFile(filepath.Join(home, ".my.cnf"), []byte("[client]\npassword=from_my_cnf\n"), 0600)
+	passwordFile := filepath.Join(home, "db_password")
+	ioutil.WriteFile(passwordFile, []byte("from_file\n"), 0600)
+
+	conset := &ConnectionSettings{driver: "mysql", user: "root", dbname: "shop", dbhost: "db"}
+	if err := conset.applyCredentials(passwordFile, false); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "from_file", conset.password)
+
+	conset = &ConnectionSettings{driver: "mysql", user: "root", dbname: "shop", dbhost: "db"}
+	err := conset.applyCredentials(filepath.Join(home, "not_existing"), false)
+	if err == nil {
+		t.Errorf("Expected error of missing password file, but got nil")
+	}
+}
+
+func TestApplyCredentialsPgpass(t *testing.T) {
+	home, cleanup := withHomeDir(t)
+	defer cleanup()
+	os.Setenv("PGPASSFILE", "")
+	ioutil.WriteFile(filepath.Join(home, ".my.cnf"), []byte("[client]\npassword=from_my_cnf\n"), 0600)
+	pgpass := "# comment\n" +
+		"other:3306:shop:root:other_host\n" +
+		"db:3306:shop:root:pa\\:ss\\\\\n" +
+		"*:*:*:reader:any_host\n"
+	pgpassFile := filepath.Join(home, ".pgpass")
+	ioutil.WriteFile(pgpassFile, []byte(pgpass), 0600)
+
+	conset := &ConnectionSettings{driver: "mysql", user: "root", dbname: "shop", dbhost: "db"}
+	if err := conset.applyCredentials("", false); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "pa:ss\\", conset.password)
+
+	conset = &ConnectionSettings{driver: "mysql", user: "reader", dbname: "logs", dbhost: "replica", port: "3307"}
+	conset.applyCredentials("", false)
+	assert.Equal(t, "any_host", conset.password)
+
+	conset = &ConnectionSettings{driver: "mysql", user: "root", dbname: "shop", dbhost: "db", port: "3307"}
+	conset.applyCredentials("", false)
+	assert.Equal(t, "from_my_cnf", conset.password)
+
+	os.Chmod(pgpassFile, 0644)
+	conset = &ConnectionSettings{driver: "mysql", user: "root", dbname: "shop", dbhost: "db"}
+	conset.applyCredentials("", false)
+	assert.Equal(t, "from_my_cnf", conset.password)
+}
+
+func TestApplyCredentialsAskPassword(t *testing.T) {
+	_, cleanup := withHomeDir(t)
+	defer cleanup()
+	originalReadPassword := readPassword
+	defer func() {
+		readPassword = originalReadPassword
+	}()
+
+	var actualPrompt string
+	readPassword = func(prompt string) (string, error) {
+		actualPrompt = prompt
+		return "typed", nil
+	}
+	conset := &ConnectionSettings{driver: "mysql", user: "root", password: "from_env", dbname: "shop"}
+	if err := conset.applyCredentials("", true); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "typed", conset.password)
+	assert.Equal(t, "Password for root@shop: ", actualPrompt)
+
+	readPassword = func(prompt string) (string, error) {
+		return "", fmt.Errorf("not a terminal")
+	}
+	err := conset.applyCredentials("", true)
+	assert.EqualError(t, err, "Fail to read password from terminal: not a terminal")
+}
+
+func TestGetConnectionSettingsWithPasswordFile(t *testing.T) {
+	home, cleanup := withHomeDir(t)
+	defer cleanup()
+	passwordFile := filepath.Join(home, "db_password")
+	ioutil.WriteFile(passwordFile, []byte("s3cret"), 0600)
+	os.Setenv("DB_PASSWORD_FILE", passwordFile)
+	defer os.Setenv("DB_PASSWORD_FILE", "")
+
+	conset, err := getConnectionSettings(&ConnectionOptions{configFile: "not_existing_file", dsn: "user@tcp(db:3306)/shop"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	cfg, err := conset.mysqlConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "s3cret", cfg.Passwd)
+}
@@ -0,0 +1,122 @@
+package dumper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QueryBuilder builds Query step by step
+type QueryBuilder struct {
+	query *Query
+	// relationTables contains names of tables of relations as they were passed to Relation
+	relationTables [][]string
+	err            error
+}
+
+// NewQueryBuilder builds new QueryBuilder
+func NewQueryBuilder() *QueryBuilder {
+	return &QueryBuilder{
+		query: &Query{
+			tables:    make([]*QueryTable, 0),
+			relations: make([]*QueryRelation, 0),
+		},
+	}
+}
+
+// Table adds table with columns. The first column of the first table is used for interval.
+func (b *QueryBuilder) Table(name string, columns ...string) *QueryBuilder {
+	return b.TableAs(name, "", columns...)
+}
+
+// TableAs adds table with alias, so the same table can be used several times with different roles
+func (b *QueryBuilder) TableAs(name string, alias string, columns ...string) *QueryBuilder {
+	return b.TableIn("", name, alias, columns...)
+}
+
+// TableIn adds table of schema with alias. Empty schema means current database, empty alias means no alias.
+// Names are used as they are, so they can contain dots and quotes.
+func (b *QueryBuilder) TableIn(schema string, name string, alias string, columns ...string) *QueryBuilder {
+	if b.err != nil {
+		return b
+	}
+	if name == "" {
+		b.err = fmt.Errorf("Found empty table name")
+		return b
+	}
+	if len(columns) == 0 {
+		b.err = fmt.Errorf("Table '%s' should contain one column at least", qualifiedName(schema, name))
+		return b
+	}
+	queryTable := &QueryTable{name, columns, alias, schema}
+	for _, qt := range b.query.tables {
+		if qt.ref() == queryTable.ref() {
+			b.err = fmt.Errorf("Table '%s' is defined twice. Use aliases", queryTable.ref())
+			return b
+		}
+	}
+	b.query.tables = append(b.query.tables, queryTable)
+	return b
+}
+
+// Relation adds relation between columns of tables. Aliased tables are referenced by alias,
+// other tables are referenced by name without schema.
+func (b *QueryBuilder) Relation(table1 string, column1 string, table2 string, column2 string) *QueryBuilder {
+	if b.err != nil {
+		return b
+	}
+	if table1 == "" || column1 == "" || table2 == "" || column2 == "" {
+		b.err = fmt.Errorf("Found empty relation part: table or column")
+		return b
+	}
+	b.relationTables = append(b.relationTables, []string{table1, table2})
+	b.query.relations = append(b.query.relations, &QueryRelation{table1, column1, table2, column2})
+	return b
+}
+
+// tableRef returns reference to table in format of request by alias or name of table
+func (b *QueryBuilder) tableRef(name string) (string, error) {
+	refs := make([]string, 0)
+	for _, qt := range b.query.tables {
+		if qt.alias == name || qt.alias == "" && qt.name == name {
+			refs = append(refs, qt.ref())
+		}
+	}
+	if len(refs) > 1 {
+		return "", fmt.Errorf("Table '%s' of relation is ambiguous: %s. Use aliases", name, strings.Join(refs, ", "))
+	}
+	if len(refs) == 0 {
+		return formatIdentifier(name), nil
+	}
+	return refs[0], nil
+}
+
+// Interval sets interval of values for the first column in the first table
+func (b *QueryBuilder) Interval(from int64, to int64) *QueryBuilder {
+	b.query.primaryInterval = []int64{from, to}
+	return b
+}
+
+// Build returns query or the first error of building
+func (b *QueryBuilder) Build() (*Query, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.query.tables) == 0 {
+		return nil, fmt.Errorf("Query should contain one table at least")
+	}
+	if len(b.query.primaryInterval) != 2 {
+		return nil, fmt.Errorf("Interval is not set")
+	}
+	for i, qr := range b.query.relations {
+		var err error
+		qr.table1, err = b.tableRef(b.relationTables[i][0])
+		if err != nil {
+			return nil, err
+		}
+		qr.table2, err = b.tableRef(b.relationTables[i][1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.query, nil
+}
@@ -0,0 +1,113 @@
+package dumper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	query, err := NewQueryBuilder().
+		Table("routes", "id", "name").
+		TableAs("stations", "start", "id").
+		Relation("routes", "start_id", "start", "id").
+		Interval(1000, 2000).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := &Query{
+		tables: []*QueryTable{
+			{"routes", []string{"id", "name"}, "", ""},
+			{"stations", []string{"id"}, "start", ""},
+		},
+		relations: []*QueryRelation{
+			{"routes", "start_id", "start", "id"},
+		},
+		primaryInterval: []int64{1000, 2000},
+	}
+	assert.Equal(t, convertQueryToString(expected), convertQueryToString(query))
+}
+
+func TestQueryBuilderErrors(t *testing.T) {
+	builders := map[string]*QueryBuilder{
+		"empty name":     NewQueryBuilder().Table("", "id").Interval(1, 2),
+		"no columns":     NewQueryBuilder().Table("routes").Interval(1, 2),
+		"twice":          NewQueryBuilder().Table("routes", "id").Table("routes", "name").Interval(1, 2),
+		"empty relation": NewQueryBuilder().Table("routes", "id").Relation("routes", "", "stations", "id").Interval(1, 2),
+		"no tables":      NewQueryBuilder().Interval(1, 2),
+		"no interval":    NewQueryBuilder().Table("routes", "id"),
+	}
+	for name, builder := range builders {
+		_, err := builder.Build()
+		if err == nil {
+			t.Errorf("Expected error for %s, but got nil", name)
+		}
+	}
+}
+
+func TestQueryBuilderWithDotsAndQuotes(t *testing.T) {
+	query, err := NewQueryBuilder().
+		Table("my.table", "id").
+		Table("my\"tab`le", "id", "parent_id").
+		Relation("my.table", "id", "my\"tab`le", "parent_id").
+		Interval(1, 2).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sql, err := query.toSqlForRelation(query.tables[1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "SELECT `my\"tab``le`.`id`, `my\"tab``le`.`parent_id`\n" +
+		"FROM `my\"tab``le`\n" +
+		"WHERE `my\"tab``le`.`parent_id` IN\n" +
+		"(\n" +
+		"SELECT `my.table`.`id`\n" +
+		"FROM `my.table`\n" +
+		"WHERE (`my.table`.`id` BETWEEN ? AND ?)\n" +
+		")"
+	assert.Equal(t, expected, sql)
+}
+
+func TestQueryBuilderTableIn(t *testing.T) {
+	query, err := NewQueryBuilder().
+		TableIn("core", "customers", "", "id").
+		TableIn("billing", "invoices", "", "id", "customer_id").
+		TableIn("core", "customers", "managers", "id").
+		Relation("customers", "id", "invoices", "customer_id").
+		Relation("managers", "id", "invoices", "id").
+		Interval(1, 2).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := &Query{
+		tables: []*QueryTable{
+			{"customers", []string{"id"}, "", "core"},
+			{"invoices", []string{"id", "customer_id"}, "", "billing"},
+			{"customers", []string{"id"}, "managers", "core"},
+		},
+		relations: []*QueryRelation{
+			{"core.customers", "id", "billing.invoices", "customer_id"},
+			{"managers", "id", "billing.invoices", "id"},
+		},
+		primaryInterval: []int64{1, 2},
+	}
+	assert.Equal(t, convertQueryToString(expected), convertQueryToString(query))
+
+	sql, err := query.toSqlForRelation(query.tables[1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Contains(t, sql, "FROM `billing`.`invoices`\nWHERE `billing`.`invoices`.`customer_id` IN")
+	assert.Contains(t, sql, "WHERE (`core`.`customers`.`id` BETWEEN ? AND ?)")
+
+	_, err = NewQueryBuilder().
+		TableIn("core", "customers", "", "id").
+		TableIn("crm", "customers", "", "id").
+		Relation("customers", "id", "customers", "id").
+		Interval(1, 2).
+		Build()
+	assert.EqualError(t, err, "Table 'customers' of relation is ambiguous: core.customers, crm.customers. Use aliases")
+}
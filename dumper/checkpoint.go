@@ -0,0 +1,308 @@
+package dumper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Checkpoint contains settings and progress of dumping which allow to resume it after failure
+type Checkpoint struct {
+	Args        []string          `json:"args"`
+	Format      string            `json:"format"`
+	File        string            `json:"file"`
+	Dir         string            `json:"dir"`
+	FormatFlags map[string]string `json:"format_flags"`
+	ChunkSize   int64             `json:"chunk_size"`
+	// Masking contains rules of masking, so resumed dump masks rows in the same way
+	Masking []string `json:"masking"`
+	// MaskingSecret is fingerprint of secret of pseudo method, secret itself is not saved
+	MaskingSecret   string                    `json:"masking_secret"`
+	DedupSpillLimit int                       `json:"dedup_spill_limit"`
+	DedupSpillDir   string                    `json:"dedup_spill_dir"`
+	DDLWritten      bool                      `json:"ddl_written"`
+	Tables          map[string]*TableProgress `json:"tables"`
+	Files           map[string]int64          `json:"files"`
+	filename        string
+	resumed         bool
+	logger          *slog.Logger
+}
+
+// TableProgress contains the last key of interval which was written for table
+// and size of its output file after writing
+type TableProgress struct {
+	LastKey int64 `json:"last_key"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// ResumableFileWriter is implemented by file writers which can continue writing into existing files
+type ResumableFileWriter interface {
+	ResumeFile(filename string, size int64) error
+}
+
+// DirectFileWriter is implemented by file writers which write into temporary files by default.
+// Checkpoint needs sizes of target files, so they are written directly.
+type DirectFileWriter interface {
+	WriteDirectly()
+}
+
+// HeaderSkipper is implemented by writers which write header once per file
+type HeaderSkipper interface {
+	SkipHeader(filename string)
+}
+
+// NewCheckpoint builds new Checkpoint which will be saved into file. File should not exist.
+func NewCheckpoint(filename string, args []string, format string, dstFile string, dstDir string, formatFlags map[string]string, chunkSize int64) (*Checkpoint, error) {
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Checkpoint file '%s' already exists. Use --resume to continue dumping", filename)
+	}
+	return &Checkpoint{
+		Args:        args,
+		Format:      format,
+		File:        dstFile,
+		Dir:         dstDir,
+		FormatFlags: formatFlags,
+		ChunkSize:   chunkSize,
+		Tables:      make(map[string]*TableProgress),
+		Files:       make(map[string]int64),
+		filename:    filename,
+		logger:      discardLogger,
+	}, nil
+}
+
+// ReadCheckpoint reads checkpoint of failed dump
+func ReadCheckpoint(filename string) (*Checkpoint, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to read checkpoint file: %v", err)
+	}
+	checkpoint := &Checkpoint{}
+	err = json.Unmarshal(contents, checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to parse checkpoint file: %v", err)
+	}
+	if checkpoint.Tables == nil {
+		checkpoint.Tables = make(map[string]*TableProgress)
+	}
+	if checkpoint.Files == nil {
+		checkpoint.Files = make(map[string]int64)
+	}
+	checkpoint.filename = filename
+	checkpoint.resumed = true
+	checkpoint.logger = discardLogger
+	return checkpoint, nil
+}
+
+// Filename returns name of checkpoint file
+func (c *Checkpoint) Filename() string {
+	return c.filename
+}
+
+// isDone returns true if rows of table were written up to the key
+func (c *Checkpoint) isDone(tableName string, key int64) bool {
+	if c == nil {
+		return false
+	}
+	progress, ok := c.Tables[tableName]
+	return ok && progress.LastKey >= key
+}
+
+// isDDLWritten returns true if DDL should not be written again
+func (c *Checkpoint) isDDLWritten() bool {
+	return c != nil && c.DDLWritten
+}
+
+// commitDDL saves sizes of files after writing DDL
+func (c *Checkpoint) commitDDL(filenames []string) error {
+	if c == nil {
+		return nil
+	}
+	for _, filename := range filenames {
+		size, err := getFileSize(filename)
+		if err != nil {
+			return err
+		}
+		c.Files[filename] = size
+	}
+	c.DDLWritten = true
+	return c.save()
+}
+
+// commit saves progress of table after writing its rows up to the key into file
+func (c *Checkpoint) commit(tableName string, key int64, filename string) error {
+	if c == nil {
+		return nil
+	}
+	size, err := getFileSize(filename)
+	if err != nil {
+		return err
+	}
+	c.Tables[tableName] = &TableProgress{key, size}
+	c.Files[filename] = size
+	return c.save()
+}
+
+// prepare saves new checkpoint or prepares output files to continue writing from checkpoint
+func (c *Checkpoint) prepare(query *Query, writer DataWriter, options *dumpOptions) error {
+	combined := options.combined
+	fw := options.fileWriter
+	if options.logger != nil {
+		c.logger = options.logger
+	}
+	if _, ok := writer.(FileNamer); !ok {
+		return fmt.Errorf("Checkpoints are available only for output into files")
+	}
+	if compressed, ok := fw.(CompressedFileWriter); ok && compressed.Compression() != nil {
+		return fmt.Errorf("Checkpoints are not available for compressed output")
+	}
+	if getTargetName(writer, "combined") == Stdout {
+		return fmt.Errorf("Checkpoints are not available for output into stdout")
+	}
+	if direct, ok := fw.(DirectFileWriter); ok {
+		direct.WriteDirectly()
+	}
+	err := c.checkSettings(options)
+	if err != nil {
+		return err
+	}
+	if !c.resumed {
+		return c.save()
+	}
+	filenames := make([]string, 0)
+	if combined {
+		filenames = append(filenames, getTargetName(writer, "combined"))
+	}
+	for _, qt := range query.tables {
+		filename := getTargetName(writer, qt.fullName())
+		if !contains(filenames, filename) {
+			filenames = append(filenames, filename)
+		}
+	}
+	return c.prepareOutputs(filenames, writer, fw)
+}
+
+// prepareOutputs truncates output files to sizes from checkpoint and allows writers to append to them
+func (c *Checkpoint) prepareOutputs(filenames []string, writer DataWriter, fw FileWriter) error {
+	resumable, ok := fw.(ResumableFileWriter)
+	if !ok {
+		return fmt.Errorf("Resuming is not supported by file writer")
+	}
+	if !c.DDLWritten {
+		c.Files = make(map[string]int64)
+	}
+	for _, filename := range filenames {
+		size := c.Files[filename]
+		err := resumable.ResumeFile(filename, size)
+		if err != nil {
+			return err
+		}
+		if skipper, ok := writer.(HeaderSkipper); ok && size > 0 {
+			skipper.SkipHeader(filename)
+		}
+	}
+	return nil
+}
+
+// checkSettings saves settings of masking and deduplication into new checkpoint
+// or checks that resumed dump uses the same settings, so rows of one output are processed in the same way
+func (c *Checkpoint) checkSettings(options *dumpOptions) error {
+	masking := make([]string, 0, len(options.maskingRules))
+	for _, rule := range options.maskingRules {
+		masking = append(masking, rule.definition())
+	}
+	secret := secretFingerprint(options.maskingSecret)
+	if !c.resumed {
+		c.Masking = masking
+		c.MaskingSecret = secret
+		c.DedupSpillLimit = options.dedupSpillLimit
+		c.DedupSpillDir = options.dedupSpillDir
+		return nil
+	}
+	if strings.Join(c.Masking, ";") != strings.Join(masking, ";") {
+		return fmt.Errorf("Masking rules differ from rules of dump in checkpoint: '%s'", strings.Join(c.Masking, ";"))
+	}
+	if c.MaskingSecret != secret {
+		return fmt.Errorf("Masking secret differs from secret of dump in checkpoint")
+	}
+	if c.DedupSpillLimit != options.dedupSpillLimit || c.DedupSpillDir != options.dedupSpillDir {
+		return fmt.Errorf("Settings of deduplication differ from settings of dump in checkpoint: spill limit %d, spill directory '%s'", c.DedupSpillLimit, c.DedupSpillDir)
+	}
+	return nil
+}
+
+// secretFingerprint returns hash which tells that secrets are equal without revealing secret
+func secretFingerprint(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("sql-dumper checkpoint"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// save writes checkpoint into temporary file and renames it, so checkpoint file is never half-written
+func (c *Checkpoint) save() error {
+	contents, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpFilename := c.filename + ".tmp"
+	err = ioutil.WriteFile(tmpFilename, contents, 0644)
+	if err != nil {
+		return fmt.Errorf("Fail to write checkpoint file: %v", err)
+	}
+	err = os.Rename(tmpFilename, c.filename)
+	if err != nil {
+		return err
+	}
+	c.logger.Debug("Checkpoint is saved", "file", c.filename)
+	return nil
+}
+
+// remove deletes checkpoint file after successful dump
+func (c *Checkpoint) remove() error {
+	err := os.Remove(c.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err == nil {
+		c.logger.Info("Checkpoint is removed after successful dump", "file", c.filename)
+	}
+	return err
+}
+
+func getFileSize(filename string) (int64, error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// splitInterval splits interval into chunks with chunkSize values in every chunk. Zero chunkSize means one chunk.
+func splitInterval(from int64, to int64, chunkSize int64) (chunks [][]int64) {
+	if chunkSize <= 0 {
+		return [][]int64{{from, to}}
+	}
+	chunks = make([][]int64, 0)
+	for start := from; start <= to; start += chunkSize {
+		end := start + chunkSize - 1
+		if end > to || end < start {
+			end = to
+		}
+		chunks = append(chunks, []int64{start, end})
+		if end == to {
+			break
+		}
+	}
+	return chunks
+}
@@ -0,0 +1,145 @@
+package dumper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitInterval(t *testing.T) {
+	assert.Equal(t, [][]int64{{1, 10}}, splitInterval(1, 10, 0))
+	assert.Equal(t, [][]int64{{1, 4}, {5, 8}, {9, 10}}, splitInterval(1, 10, 4))
+	assert.Equal(t, [][]int64{{1, 5}, {6, 10}}, splitInterval(1, 10, 5))
+	assert.Equal(t, [][]int64{{3, 3}}, splitInterval(3, 3, 100))
+}
+
+func TestNewCheckpointFileExists(t *testing.T) {
+	_, err := NewCheckpoint("query.go", []string{}, "sql", "", "", nil, 0)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestCheckpointCommitAndRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	outputFile := filepath.Join(dir, "result.sql")
+	checkpointFile := filepath.Join(dir, "checkpoint.json")
+	ioutil.WriteFile(outputFile, []byte("some data"), 0644)
+
+	checkpoint, err := NewCheckpoint(checkpointFile, []string{"routes:id", "1-10"}, "sql", outputFile, "", nil, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = checkpoint.commit("routes", 5, outputFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	readCheckpoint, err := ReadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, []string{"routes:id", "1-10"}, readCheckpoint.Args)
+	assert.Equal(t, int64(5), readCheckpoint.ChunkSize)
+	assert.Equal(t, int64(9), readCheckpoint.Files[outputFile])
+	assert.True(t, readCheckpoint.isDone("routes", 5))
+	assert.False(t, readCheckpoint.isDone("routes", 10))
+	assert.False(t, readCheckpoint.isDone("stations", 5))
+
+	var nilCheckpoint *Checkpoint
+	assert.False(t, nilCheckpoint.isDone("routes", 5))
+}
+
+func TestReadCheckpointError(t *testing.T) {
+	_, err := ReadCheckpoint("not_existing_checkpoint.json")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	_, err = ReadCheckpoint("query.go")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestResumeFileShorterThanCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	outputFile := filepath.Join(dir, "result.sql")
+	ioutil.WriteFile(outputFile, []byte("abc"), 0644)
+
+	fw := NewOsFileWriter()
+	err = fw.ResumeFile(outputFile, 10)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	err = fw.ResumeFile(outputFile, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err := fw.GetFileHandler(outputFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("d")
+	f.Close()
+	contents, _ := ioutil.ReadFile(outputFile)
+	assert.Equal(t, "abd", string(contents))
+}
+
+func TestCheckpointSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	checkpointFile := filepath.Join(dir, "checkpoint.json")
+
+	rules, err := ParseMaskingRules("users.email=hash;users.phone=pseudo:phone")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	options := &dumpOptions{maskingRules: rules, maskingSecret: "secret", dedupSpillLimit: 100, dedupSpillDir: dir}
+
+	checkpoint, err := NewCheckpoint(checkpointFile, []string{"users:id", "1-10"}, "sql", "", "", nil, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = checkpoint.checkSettings(options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = checkpoint.save()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	readCheckpoint, err := ReadCheckpoint(checkpointFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, []string{"users.email=hash", "users.phone=pseudo:phone"}, readCheckpoint.Masking)
+	assert.NotEmpty(t, readCheckpoint.MaskingSecret)
+	assert.NotContains(t, readCheckpoint.MaskingSecret, "secret")
+	assert.Equal(t, 100, readCheckpoint.DedupSpillLimit)
+	assert.Equal(t, dir, readCheckpoint.DedupSpillDir)
+
+	err = readCheckpoint.checkSettings(options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = readCheckpoint.checkSettings(&dumpOptions{maskingRules: rules[:1], maskingSecret: "secret", dedupSpillLimit: 100, dedupSpillDir: dir})
+	assert.EqualError(t, err, "Masking rules differ from rules of dump in checkpoint: 'users.email=hash;users.phone=pseudo:phone'")
+	err = readCheckpoint.checkSettings(&dumpOptions{maskingRules: rules, maskingSecret: "other", dedupSpillLimit: 100, dedupSpillDir: dir})
+	assert.EqualError(t, err, "Masking secret differs from secret of dump in checkpoint")
+	err = readCheckpoint.checkSettings(&dumpOptions{maskingRules: rules, maskingSecret: "secret"})
+	assert.EqualError(t, err, "Settings of deduplication differ from settings of dump in checkpoint: spill limit 100, spill directory '"+dir+"'")
+}
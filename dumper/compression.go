@@ -0,0 +1,100 @@
+package dumper
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Compression describes algorithm of compression of output files
+type Compression struct {
+	// Name is used to choose compression, e.g. in --compress
+	Name string
+	// Extension is added to names of compressed files
+	Extension string
+	// NewWriter wraps file with compressor. Compressed data is flushed by Close of compressor.
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+	// NewReader wraps compressed file with decompressor, e.g. for restoring of dump
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var compressions = map[string]*Compression{
+	"gzip": {
+		Name:      "gzip",
+		Extension: "gz",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	"zstd": {
+		Name:      "zstd",
+		Extension: "zst",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return decoder.IOReadCloser(), nil
+		},
+	},
+}
+
+// LookupCompression returns compression by name. Empty name and none mean output without compression.
+func LookupCompression(name string) (*Compression, error) {
+	if name == "" || name == "none" {
+		return nil, nil
+	}
+	compression, ok := compressions[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown compression '%s'. Available compressions: %s", name, strings.Join(Compressions(), ", "))
+	}
+	return compression, nil
+}
+
+// DetectCompression returns compression by extension of filename, e.g. gzip for result.sql.gz, or nil
+func DetectCompression(filename string) *Compression {
+	for _, compression := range compressions {
+		if strings.HasSuffix(filename, "."+compression.Extension) {
+			return compression
+		}
+	}
+	return nil
+}
+
+// Compressions returns sorted names of available compressions
+func Compressions() []string {
+	names := make([]string, 0, len(compressions))
+	for name := range compressions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CompressedFileWriter is implemented by file writers which compress output files
+type CompressedFileWriter interface {
+	Compression() *Compression
+}
+
+// withCompressionExtension adds extension of compression of file writer to filename if it does not have it.
+// Name of stdout is not changed.
+func withCompressionExtension(fw FileWriter, filename string) string {
+	compressed, ok := fw.(CompressedFileWriter)
+	if !ok || compressed.Compression() == nil || filename == Stdout {
+		return filename
+	}
+	extension := "." + compressed.Compression().Extension
+	if strings.HasSuffix(filename, extension) {
+		return filename
+	}
+	return filename + extension
+}
@@ -0,0 +1,123 @@
+package dumper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupCompression(t *testing.T) {
+	compression, err := LookupCompression("gzip")
+	assert.Nil(t, err)
+	assert.Equal(t, "gz", compression.Extension)
+
+	compression, err = LookupCompression("none")
+	assert.Nil(t, err)
+	assert.Nil(t, compression)
+
+	_, err = LookupCompression("bzip2")
+	assert.EqualError(t, err, "Unknown compression 'bzip2'. Available compressions: gzip, zstd")
+}
+
+func TestDetectCompression(t *testing.T) {
+	assert.Equal(t, "gzip", DetectCompression("result.sql.gz").Name)
+	assert.Equal(t, "zstd", DetectCompression("dumps/result.csv.zst").Name)
+	assert.Nil(t, DetectCompression("result.sql"))
+	assert.Nil(t, DetectCompression(""))
+}
+
+func TestWithCompressionExtension(t *testing.T) {
+	gzipCompression, _ := LookupCompression("gzip")
+	fw := NewOsFileWriter(WithCompression(gzipCompression))
+	assert.Equal(t, "result.sql.gz", withCompressionExtension(fw, "result.sql"))
+	assert.Equal(t, "result.sql.gz", withCompressionExtension(fw, "result.sql.gz"))
+	assert.Equal(t, "result.sql", withCompressionExtension(NewOsFileWriter(), "result.sql"))
+	assert.Equal(t, "result.sql", withCompressionExtension(NewMemoryFileWriter(), "result.sql"))
+	assert.Equal(t, Stdout, withCompressionExtension(fw, Stdout))
+
+	writer := NewSqlWriter(fw, "", "dumps")
+	assert.Equal(t, "dumps/routes.sql.gz", writer.Filename("routes"))
+}
+
+func TestCompressedFileWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compression")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	readers := map[string]func(r io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		"zstd": func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r)
+		},
+	}
+	for name, newReader := range readers {
+		compression, _ := LookupCompression(name)
+		fw := NewOsFileWriter(WithCompression(compression))
+		filename := filepath.Join(dir, "result.sql."+compression.Extension)
+		for _, s := range []string{"first;\n", "second;\n"} {
+			f, err := fw.GetFileHandler(filename)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			f.WriteString(s)
+			f.Close()
+		}
+		if err = fw.Close(); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		reader, err := newReader(file)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", name, err)
+		}
+		contents, err := ioutil.ReadAll(reader)
+		file.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", name, err)
+		}
+		assert.Equal(t, "first;\nsecond;\n", string(contents), name)
+	}
+}
+
+func TestCompressionNewReader(t *testing.T) {
+	for _, name := range Compressions() {
+		compression, _ := LookupCompression(name)
+		compressed := &bytes.Buffer{}
+		writer, err := compression.NewWriter(compressed)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", name, err)
+		}
+		writer.Write([]byte("INSERT INTO `routes` (`id`) VALUES (1);\n"))
+		writer.Close()
+
+		reader, err := compression.NewReader(compressed)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", name, err)
+		}
+		contents, err := ioutil.ReadAll(reader)
+		reader.Close()
+		assert.Nil(t, err, name)
+		assert.Equal(t, "INSERT INTO `routes` (`id`) VALUES (1);\n", string(contents), name)
+	}
+}
+
+func TestCompressedFileWriterResume(t *testing.T) {
+	gzipCompression, _ := LookupCompression("gzip")
+	fw := NewOsFileWriter(WithCompression(gzipCompression))
+	err := fw.ResumeFile("result.sql.gz", 0)
+	assert.EqualError(t, err, "Compressed file 'result.sql.gz' can not be resumed")
+}
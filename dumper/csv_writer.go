@@ -0,0 +1,135 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	RegisterFormat(&Format{
+		Name:      "csv",
+		Extension: "csv",
+		Combined:  true,
+		Separated: true,
+		Flags: []*FormatFlag{
+			{"csv-delimiter", ",", "Sets delimiter of values in CSV"},
+		},
+		NewWriter: func(fw FileWriter, dstFile string, dstDir string, flags map[string]string) (DataWriter, error) {
+			if flags["csv-delimiter"] == "" {
+				return nil, fmt.Errorf("Delimiter for csv format should not be empty")
+			}
+			return NewCsvWriter(fw, dstFile, dstDir, flags["csv-delimiter"]), nil
+		},
+	})
+}
+
+// CsvWriter writes data in csv format using FileWriter
+type CsvWriter struct {
+	fw        FileWriter
+	dstFile   string
+	dstDir    string
+	delimiter string
+	headers   map[string]bool
+}
+
+// NewCsvWriter builds new CsvWriter
+func NewCsvWriter(fw FileWriter, dstFile, dstDir, delimiter string) *CsvWriter {
+	return &CsvWriter{
+		fw,
+		dstFile,
+		dstDir,
+		delimiter,
+		make(map[string]bool),
+	}
+}
+
+// WriteDDL is part of interface. It is not useful for csv.
+func (w *CsvWriter) WriteDDL(ctx context.Context, tableName string, ddl string) (err error) {
+	return
+}
+
+// WriteRows writes result rows in csv format
+func (w *CsvWriter) WriteRows(ctx context.Context, tableName string, columns []string, rows []*map[string]interface{}) (err error) {
+	filename := w.Filename(tableName)
+	f, err := w.fw.GetFileHandler(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if appended, ok := f.(AppendedFile); ok && appended.IsAppended() {
+		w.headers[filename] = true
+	}
+	if !w.headers[filename] {
+		columnsNames := make([]string, 0)
+		for _, column := range columns {
+			columnsNames = append(columnsNames, escapeCsvString(column))
+		}
+		_, err = f.WriteString(strings.Join(columnsNames, w.delimiter) + "\r\n")
+		if err != nil {
+			return fmt.Errorf("Error at writing header to file: %s", err)
+		}
+		w.headers[filename] = true
+		loggerFromContext(ctx).Debug("CSV header is written into file", "file", filename)
+	}
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		values := make([]string, 0)
+		for _, field := range columns {
+			v := (*row)[field]
+			value := ""
+			switch typedValue := v.(type) {
+			case int:
+				value = fmt.Sprintf("%d", typedValue)
+				break
+			case int64:
+				value = fmt.Sprintf("%d", typedValue)
+				break
+			case float64:
+				value = fmt.Sprintf("%f", typedValue)
+				break
+			case string:
+				value = escapeCsvString(typedValue)
+				break
+			case []uint8:
+				value = escapeCsvString(fmt.Sprintf("%s", typedValue))
+			case nil:
+				value = "NULL"
+			default:
+				value = "UNDEFINED"
+			}
+			values = append(values, value)
+		}
+		_, err = f.WriteString(strings.Join(values, w.delimiter) + "\r\n")
+		if err != nil {
+			return fmt.Errorf("Error at writing rows to file: %s", err)
+		}
+	}
+	loggerFromContext(ctx).Debug("Rows are written into file", "table", tableName, "file", filename, "rows", len(rows))
+	return
+}
+
+// SkipHeader marks file as already containing header
+func (w *CsvWriter) SkipHeader(filename string) {
+	w.headers[filename] = true
+}
+
+// FileWriter returns file writer which writes files of writer
+func (w *CsvWriter) FileWriter() FileWriter {
+	return w.fw
+}
+
+// Filename returns name of file for rows of table
+func (w *CsvWriter) Filename(tableName string) (filename string) {
+	if w.dstDir != "" {
+		return withCompressionExtension(w.fw, tableFilename(w.dstDir, tableName, "csv"))
+	}
+	return withCompressionExtension(w.fw, w.dstFile)
+}
+
+func escapeCsvString(str string) string {
+	str = strings.Replace(str, "\"", "\"\"", -1)
+	return "\"" + str + "\""
+}
@@ -1,9 +1,12 @@
-package main
+package dumper
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestCsvWriterWriteRows(t *testing.T) {
-	fw := NewTestFileWriter()
+	fw := NewMemoryFileWriter()
 	writer := NewCsvWriter(fw, "result.csv", "", ",")
 	rows := make([]*map[string]interface{}, 0)
 	rows = append(rows, &map[string]interface{}{
@@ -27,8 +30,8 @@ func TestCsvWriterWriteRows(t *testing.T) {
 		"strange": uintptr(1),
 	})
 
-	writer.WriteRows("some_table", []string{"name", "title", "id", "value", "amount", "chars", "nulled", "strange"}, rows)
-	result := fw.getContents("result.csv")
+	writer.WriteRows(context.Background(), "some_table", []string{"name", "title", "id", "value", "amount", "chars", "nulled", "strange"}, rows)
+	result := fw.Contents("result.csv")
 	expected := "\"name\",\"title\",\"id\",\"value\",\"amount\",\"chars\",\"nulled\",\"strange\"\r\n"
 	expected += "\"one\",\"t\"\"wo\",123,456,1.230000,\"&#)\",NULL,UNDEFINED\r\n"
 	expected += "\"four\",\"five\",789,345,2.230000,\"##)\",NULL,UNDEFINED\r\n"
@@ -38,7 +41,7 @@ func TestCsvWriterWriteRows(t *testing.T) {
 }
 
 func TestCsvWriterWriteRowsToDir(t *testing.T) {
-	fw := NewTestFileWriter()
+	fw := NewMemoryFileWriter()
 	writer := NewCsvWriter(fw, "", "/tmp/some_dir", ";")
 	rows1 := make([]*map[string]interface{}, 0)
 	rows1 = append(rows1, &map[string]interface{}{
@@ -51,10 +54,10 @@ func TestCsvWriterWriteRowsToDir(t *testing.T) {
 		"value": 5,
 	})
 
-	writer.WriteRows("some_table1", []string{"id", "name"}, rows1)
-	writer.WriteRows("some_table2", []string{"id", "value"}, rows2)
-	result1 := fw.getContents("/tmp/some_dir/some_table1.csv")
-	result2 := fw.getContents("/tmp/some_dir/some_table2.csv")
+	writer.WriteRows(context.Background(), "some_table1", []string{"id", "name"}, rows1)
+	writer.WriteRows(context.Background(), "some_table2", []string{"id", "value"}, rows2)
+	result1 := fw.Contents("/tmp/some_dir/some_table1.csv")
+	result2 := fw.Contents("/tmp/some_dir/some_table2.csv")
 	expected1 := "\"id\";\"name\"\r\n"
 	expected1 += "1;\"Name 1\"\r\n"
 	if expected1 != result1 {
@@ -68,9 +71,9 @@ func TestCsvWriterWriteRowsToDir(t *testing.T) {
 }
 
 func TestCsvWriterWriteDDL(t *testing.T) {
-	fw := NewTestFileWriter()
+	fw := NewMemoryFileWriter()
 	writer := NewCsvWriter(fw, "result.csv", "", ",")
-	err := writer.WriteDDL("some_table", "")
+	err := writer.WriteDDL(context.Background(), "some_table", "")
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err)
 	}
@@ -81,7 +84,7 @@ func TestCsvWriterWriteRowsFileWriteError(t *testing.T) {
 	writer := NewCsvWriter(fw, "result.csv", "", ",")
 	rows := make([]*map[string]interface{}, 0)
 	rows = append(rows, &map[string]interface{}{"name": "one"})
-	err := writer.WriteRows("some_table", []string{"name"}, rows)
+	err := writer.WriteRows(context.Background(), "some_table", []string{"name"}, rows)
 	if err == nil {
 		t.Errorf("Expected file writer error, but got nil")
 	}
@@ -92,7 +95,7 @@ func TestCsvWriterWriteRowsFileWriteErrorAtSecondAttempt(t *testing.T) {
 	writer := NewCsvWriter(fw, "result.csv", "", ",")
 	rows := make([]*map[string]interface{}, 0)
 	rows = append(rows, &map[string]interface{}{"name": "one"})
-	err := writer.WriteRows("some_table", []string{"name"}, rows)
+	err := writer.WriteRows(context.Background(), "some_table", []string{"name"}, rows)
 	if err == nil {
 		t.Errorf("Expected file writer error, but got nil")
 	}
@@ -103,8 +106,36 @@ func TestCsvWriterWriteRowsFileHandlerError(t *testing.T) {
 	writer := NewCsvWriter(fw, "result.csv", "", ",")
 	rows := make([]*map[string]interface{}, 0)
 	rows = append(rows, &map[string]interface{}{"name": "one"})
-	err := writer.WriteRows("some_table", []string{"name"}, rows)
+	err := writer.WriteRows(context.Background(), "some_table", []string{"name"}, rows)
 	if err == nil {
 		t.Errorf("Expected write error, but got nil")
 	}
 }
+
+func TestCsvWriterWriteRowsHeaderOnce(t *testing.T) {
+	fw := NewMemoryFileWriter()
+	writer := NewCsvWriter(fw, "result.csv", "", ",")
+	rows1 := []*map[string]interface{}{{"id": 1}}
+	rows2 := []*map[string]interface{}{{"id": 2}}
+
+	writer.WriteRows(context.Background(), "some_table", []string{"id"}, rows1)
+	writer.WriteRows(context.Background(), "some_table", []string{"id"}, rows2)
+	result := fw.Contents("result.csv")
+	expected := "\"id\"\r\n1\r\n2\r\n"
+	if expected != result {
+		t.Errorf("Expected:\n%sGot:\n%s", expected, result)
+	}
+}
+
+func TestCsvWriterSkipHeader(t *testing.T) {
+	fw := NewMemoryFileWriter()
+	writer := NewCsvWriter(fw, "result.csv", "", ",")
+	writer.SkipHeader("result.csv")
+
+	writer.WriteRows(context.Background(), "some_table", []string{"id"}, []*map[string]interface{}{{"id": 2}})
+	result := fw.Contents("result.csv")
+	expected := "2\r\n"
+	if expected != result {
+		t.Errorf("Expected:\n%sGot:\n%s", expected, result)
+	}
+}
@@ -0,0 +1,124 @@
+package dumper
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// RowsDeduplicator skips rows which were already written for the same table.
+// Rows are identified by primary key if it is selected or by all values otherwise.
+type RowsDeduplicator struct {
+	primaryKeys map[string][]string
+	aliased     map[string]bool
+	keySets     map[string]KeySet
+	newKeySet   func() KeySet
+	logger      *slog.Logger
+}
+
+// NewRowsDeduplicator builds new RowsDeduplicator.
+// Keys are kept in memory if spillLimit is 0, otherwise they are moved to spillDir after reaching the limit.
+func NewRowsDeduplicator(primaryKeys map[string][]string, aliased map[string]bool, spillLimit int, spillDir string) *RowsDeduplicator {
+	d := &RowsDeduplicator{
+		primaryKeys: primaryKeys,
+		aliased:     aliased,
+		keySets:     make(map[string]KeySet),
+		logger:      discardLogger,
+	}
+	d.newKeySet = func() KeySet {
+		return NewMemoryKeySet()
+	}
+	if spillLimit > 0 {
+		d.newKeySet = func() KeySet {
+			keySet := NewSpillKeySet(spillLimit, spillDir)
+			keySet.logger = d.logger
+			return keySet
+		}
+	}
+	return d
+}
+
+// Filter returns rows which were not passed before for the table
+func (d *RowsDeduplicator) Filter(tableName string, columns []string, rows []*map[string]interface{}) (filtered []*map[string]interface{}, err error) {
+	keyColumns := d.keyColumns(tableName, columns)
+	if keyColumns == nil {
+		return rows, nil
+	}
+	keySet, ok := d.keySets[tableName]
+	if !ok {
+		keySet = d.newKeySet()
+		d.keySets[tableName] = keySet
+	}
+	filtered = make([]*map[string]interface{}, 0)
+	for _, row := range rows {
+		added, err := keySet.Add(getRowKey(row, keyColumns))
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered, nil
+}
+
+// remembers returns true if keys of rows of table are remembered to skip duplicates
+func (d *RowsDeduplicator) remembers(tableName string, columns []string) bool {
+	return d.keyColumns(tableName, columns) != nil
+}
+
+// keyColumns returns columns which identify rows of table or nil if rows are not deduplicated
+func (d *RowsDeduplicator) keyColumns(tableName string, columns []string) []string {
+	keyColumns := d.primaryKeys[tableName]
+	if len(keyColumns) == 0 || !containsAll(columns, keyColumns) {
+		if !d.aliased[tableName] {
+			// Rows without primary key can be repeated only by selecting them through several aliases
+			return nil
+		}
+		return columns
+	}
+	return keyColumns
+}
+
+// Close releases resources of all key sets
+func (d *RowsDeduplicator) Close() (err error) {
+	for _, keySet := range d.keySets {
+		if closeErr := keySet.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return
+}
+
+// getRowKey encodes values of key columns with their lengths, so values which contain any bytes are not confused
+func getRowKey(row *map[string]interface{}, keyColumns []string) string {
+	var key strings.Builder
+	for _, column := range keyColumns {
+		value := ""
+		switch typedValue := (*row)[column].(type) {
+		case []uint8:
+			value = string(typedValue)
+		case nil:
+			// Encoded values start with length, so NULL can't be confused with any value
+			key.WriteString("-;")
+			continue
+		default:
+			value = fmt.Sprintf("%v", typedValue)
+		}
+		key.WriteString(strconv.Itoa(len(value)))
+		key.WriteByte(':')
+		key.WriteString(value)
+	}
+	return key.String()
+}
+
+func getPrimaryKeysFromTableDescription(tableDescribtion []TableColumnDDL) (primaryKeys []string) {
+	primaryKeys = make([]string, 0)
+	for _, columnDescr := range tableDescribtion {
+		if columnDescr.Key == "PRI" {
+			primaryKeys = append(primaryKeys, columnDescr.Field)
+		}
+	}
+	return
+}
@@ -0,0 +1,118 @@
+package dumper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowsDeduplicatorFilterByPrimaryKey(t *testing.T) {
+	d := NewRowsDeduplicator(map[string][]string{"users": {"id"}}, map[string]bool{}, 0, "")
+	defer d.Close()
+	columns := []string{"id", "name"}
+	rows := []*map[string]interface{}{
+		{"id": 1, "name": "one"},
+		{"id": 2, "name": "two"},
+	}
+	filtered, err := d.Filter("users", columns, rows)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	if len(filtered) != 2 {
+		t.Errorf("Expected 2 rows, got %d", len(filtered))
+	}
+	rows = []*map[string]interface{}{
+		{"id": 2, "name": "changed"},
+		{"id": 3, "name": "three"},
+	}
+	filtered, err = d.Filter("users", columns, rows)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	expected := []*map[string]interface{}{
+		{"id": 3, "name": "three"},
+	}
+	if !reflect.DeepEqual(expected, filtered) {
+		t.Errorf("Expected only new row, got %v", filtered)
+	}
+}
+
+func TestRowsDeduplicatorFilterWithoutPrimaryKey(t *testing.T) {
+	d := NewRowsDeduplicator(map[string][]string{}, map[string]bool{"users": true}, 0, "")
+	defer d.Close()
+	columns := []string{"id", "name"}
+	rows := []*map[string]interface{}{
+		{"id": 1, "name": "one"},
+		{"id": 1, "name": "one"},
+		{"id": 1, "name": "other"},
+	}
+	filtered, _ := d.Filter("users", columns, rows)
+	if len(filtered) != 2 {
+		t.Errorf("Expected 2 rows, got %d", len(filtered))
+	}
+
+	filtered, _ = d.Filter("logs", columns, rows)
+	if len(filtered) != 3 {
+		t.Errorf("Expected rows of not aliased table without primary key as is, got %d", len(filtered))
+	}
+}
+
+func TestRowsDeduplicatorFilterPrimaryKeyNotSelected(t *testing.T) {
+	d := NewRowsDeduplicator(map[string][]string{"users": {"id"}}, map[string]bool{}, 0, "")
+	defer d.Close()
+	rows := []*map[string]interface{}{
+		{"name": "one"},
+		{"name": "one"},
+	}
+	filtered, _ := d.Filter("users", []string{"name"}, rows)
+	if len(filtered) != 2 {
+		t.Errorf("Expected 2 rows, got %d", len(filtered))
+	}
+}
+
+func TestRowsDeduplicatorFilterWithSpill(t *testing.T) {
+	d := NewRowsDeduplicator(map[string][]string{"users": {"id"}}, map[string]bool{}, 2, "")
+	defer d.Close()
+	rows := make([]*map[string]interface{}, 0)
+	for i := 0; i < 10; i++ {
+		rows = append(rows, &map[string]interface{}{"id": i})
+	}
+	filtered, err := d.Filter("users", []string{"id"}, rows)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	if len(filtered) != 10 {
+		t.Errorf("Expected 10 rows, got %d", len(filtered))
+	}
+	filtered, err = d.Filter("users", []string{"id"}, rows)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	if len(filtered) != 0 {
+		t.Errorf("Expected 0 rows, got %d", len(filtered))
+	}
+}
+
+func TestGetRowKey(t *testing.T) {
+	row1 := &map[string]interface{}{"id": []uint8("1"), "code": nil}
+	row2 := &map[string]interface{}{"id": "1", "code": "NULL"}
+	if getRowKey(row1, []string{"id"}) != getRowKey(row2, []string{"id"}) {
+		t.Errorf("Expected equal keys for bytes and string")
+	}
+	if getRowKey(row1, []string{"id", "code"}) == getRowKey(row2, []string{"id", "code"}) {
+		t.Errorf("Expected different keys for NULL and string")
+	}
+	row3 := &map[string]interface{}{"id": "a\x1fb", "code": "c"}
+	row4 := &map[string]interface{}{"id": "a", "code": "b\x1fc"}
+	if getRowKey(row3, []string{"id", "code"}) == getRowKey(row4, []string{"id", "code"}) {
+		t.Errorf("Expected different keys for values with separators")
+	}
+	row5 := &map[string]interface{}{"id": "1:a", "code": ""}
+	row6 := &map[string]interface{}{"id": "", "code": "1:a"}
+	if getRowKey(row5, []string{"id", "code"}) == getRowKey(row6, []string{"id", "code"}) {
+		t.Errorf("Expected different keys for values with lengths")
+	}
+}
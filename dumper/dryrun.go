@@ -0,0 +1,79 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// dryRun prints queries with bound values, DDL and names of files instead of writing result.
+// DDL is not printed without db.
+func (q *Query) dryRun(ctx context.Context, db *sqlx.DB, writer DataWriter, combined bool, out io.Writer) (err error) {
+	if len(q.primaryInterval) != 2 {
+		return fmt.Errorf("primaryInterval should contain two values")
+	}
+
+	ddls := make(map[string]string)
+	if db != nil {
+		ddls, err = q.toDDL(ctx, db)
+		if err != nil {
+			return err
+		}
+	}
+
+	printedDDLs := make(map[string]bool)
+	for _, qt := range q.tables {
+		if ddl, ok := ddls[qt.fullName()]; ok && !printedDDLs[qt.fullName()] {
+			fmt.Fprintf(out, "-- DDL for table %s into %s\n%s\n\n", qt.fullName(), getTargetName(writer, qt.fullName()), ddl)
+			printedDDLs[qt.fullName()] = true
+		}
+	}
+
+	if combined {
+		query := bindQueryArgs(q.toSqlForCombinedRows(), q.primaryInterval[0], q.primaryInterval[1])
+		fmt.Fprintf(out, "-- Query for combined rows into %s\n%s;\n\n", getTargetName(writer, "combined"), query)
+		return nil
+	}
+
+	queries, err := q.toSqlForTables()
+	if err != nil {
+		return err
+	}
+	for i, qt := range q.tables {
+		query := bindQueryArgs(queries[i], q.primaryInterval[0], q.primaryInterval[1])
+		fmt.Fprintf(out, "-- Query for table %s into %s\n%s;\n\n", qt.ref(), getTargetName(writer, qt.fullName()), query)
+	}
+	return nil
+}
+
+func getTargetName(writer DataWriter, tableName string) string {
+	if namer, ok := writer.(FileNamer); ok {
+		return namer.Filename(tableName)
+	}
+	return "stdout"
+}
+
+// bindQueryArgs replaces placeholders with values of arguments. Question marks inside quoted identifiers are kept.
+func bindQueryArgs(query string, args ...int64) string {
+	var result strings.Builder
+	var quote rune
+	for _, ch := range query {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '`' || ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '?' && len(args) > 0:
+			result.WriteString(strconv.FormatInt(args[0], 10))
+			args = args[1:]
+			continue
+		}
+		result.WriteRune(ch)
+	}
+	return result.String()
+}
@@ -0,0 +1,134 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var query = &Query{
+		tables: []*QueryTable{
+			{"routes", []string{"id"}, "", ""},
+			{"stations_for_routes", []string{"route_id"}, "", ""},
+		},
+		relations: []*QueryRelation{
+			{"routes", "id", "stations_for_routes", "route_id"},
+		},
+		primaryInterval: []int64{10, 20},
+	}
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("DESCRIBE `stations_for_routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("route_id", "bigint(20)", "NO", "", nil, ""),
+		)
+
+	fw := NewMemoryFileWriter()
+	writer := NewSqlWriter(fw, "", "/tmp/some_dir")
+	out := &bytes.Buffer{}
+	err = query.dryRun(context.Background(), sqlxDB, writer, false, out)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	expected := "-- DDL for table routes into /tmp/some_dir/routes.sql\n" +
+		"CREATE TABLE `routes` (\n" +
+		"    `id` bigint(20) NOT NULL,\n" +
+		"    PRIMARY KEY (`id`)\n" +
+		");\n\n" +
+		"-- DDL for table stations_for_routes into /tmp/some_dir/stations_for_routes.sql\n" +
+		"CREATE TABLE `stations_for_routes` (\n" +
+		"    `route_id` bigint(20) NOT NULL,\n" +
+		"    CONSTRAINT `fk_route_id` FOREIGN KEY (`route_id`) REFERENCES `routes` (`id`) ON DELETE CASCADE\n" +
+		");\n\n" +
+		"-- Query for table routes into /tmp/some_dir/routes.sql\n" +
+		"SELECT `routes`.`id`\n" +
+		"FROM `routes`\n" +
+		"WHERE `routes`.`id` BETWEEN 10 AND 20;\n\n" +
+		"-- Query for table stations_for_routes into /tmp/some_dir/stations_for_routes.sql\n" +
+		"SELECT `stations_for_routes`.`route_id`\n" +
+		"FROM `stations_for_routes`\n" +
+		"WHERE `stations_for_routes`.`route_id` IN\n" +
+		"(\n" +
+		"SELECT `routes`.`id`\n" +
+		"FROM `routes`\n" +
+		"WHERE (`routes`.`id` BETWEEN 10 AND 20)\n" +
+		");\n\n"
+	if out.String() != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, out.String())
+	}
+	if len(fw.Filenames()) != 0 {
+		t.Errorf("Expected no files, got %d", len(fw.Filenames()))
+	}
+}
+
+func TestDryRunCombinedWithoutDDL(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := typicalQuery.dryRun(context.Background(), nil, &SimpleWriter{}, true, out)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	expected := "-- Query for combined rows into stdout\n" +
+		bindQueryArgs(typicalQuery.toSqlForCombinedRows(), 1000, 2000) + ";\n\n"
+	if out.String() != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, out.String())
+	}
+}
+
+func TestDryRunErrors(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("DESCRIBE `routes`").WillReturnError(fmt.Errorf("Some DB error"))
+	err = typicalQuery.dryRun(context.Background(), sqlxDB, &SimpleWriter{}, false, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	badQuery := &Query{
+		tables: []*QueryTable{
+			{"routes", []string{"id"}, "", ""},
+			{"stations", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 2},
+	}
+	err = badQuery.dryRun(context.Background(), nil, &SimpleWriter{}, false, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestBindQueryArgs(t *testing.T) {
+	query := bindQueryArgs("SELECT 1 WHERE id BETWEEN ? AND ?", 1, 2)
+	expected := "SELECT 1 WHERE id BETWEEN 1 AND 2"
+	if query != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, query)
+	}
+}
+
+func TestBindQueryArgsWithQuestionMarksInIdentifiers(t *testing.T) {
+	query := bindQueryArgs("SELECT `x`.`c?`, `x`.`d``?` FROM `x` WHERE `x`.`id?` BETWEEN ? AND ?", 1, 2)
+	expected := "SELECT `x`.`c?`, `x`.`d``?` FROM `x` WHERE `x`.`id?` BETWEEN 1 AND 2"
+	if query != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, query)
+	}
+}
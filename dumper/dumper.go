@@ -0,0 +1,117 @@
+// Package dumper dumps rows of related tables from MySQL into SQL or CSV files.
+//
+// Rows of the first table are selected by interval of values of its first column,
+// rows of other tables are selected by relations with already selected rows.
+package dumper
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Option changes settings of dumping
+type Option func(options *dumpOptions)
+
+// WithCombined selects rows of all tables with one query and writes them as one result
+func WithCombined() Option {
+	return func(options *dumpOptions) {
+		options.combined = true
+	}
+}
+
+// WithJobs sets number of queries for separated tables to run concurrently
+func WithJobs(jobs int) Option {
+	return func(options *dumpOptions) {
+		options.jobs = jobs
+	}
+}
+
+// WithQueryTimeout sets time limit for every query. Zero timeout means no limit.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(options *dumpOptions) {
+		options.queryTimeout = timeout
+	}
+}
+
+// WithChunkSize sets number of values of the first column to select in one chunk. Zero size means one chunk.
+func WithChunkSize(size int64) Option {
+	return func(options *dumpOptions) {
+		options.chunkSize = size
+	}
+}
+
+// WithDedupSpill sets number of primary keys per table to keep in memory for skipping duplicated rows
+// and directory for keys which are moved to disk after reaching the limit
+func WithDedupSpill(limit int, dir string) Option {
+	return func(options *dumpOptions) {
+		options.dedupSpillLimit = limit
+		options.dedupSpillDir = dir
+	}
+}
+
+// WithMasking sets rules of masking values of columns. Secret is used by pseudo method.
+func WithMasking(rules []*MaskingRule, secret string) Option {
+	return func(options *dumpOptions) {
+		options.maskingRules = rules
+		options.maskingSecret = secret
+	}
+}
+
+// WithCheckpoint saves progress into checkpoint or continues dump from checkpoint which was read.
+// File writer is used to continue writing into existing files.
+func WithCheckpoint(checkpoint *Checkpoint, fw FileWriter) Option {
+	return func(options *dumpOptions) {
+		options.checkpoint = checkpoint
+		options.fileWriter = fw
+	}
+}
+
+// WithLogger sets logger for queries, checkpoint, keys of deduplication and data writers.
+// Logs are discarded by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(options *dumpOptions) {
+		options.logger = logger
+	}
+}
+
+// WithProgress sets reporter of progress
+func WithProgress(progress *ProgressReporter) Option {
+	return func(options *dumpOptions) {
+		options.progress = progress
+	}
+}
+
+func newDumpOptions(opts []Option) *dumpOptions {
+	options := &dumpOptions{
+		jobs:   1,
+		logger: discardLogger,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// Dump selects rows of query from DB and writes them with writer
+func Dump(ctx context.Context, db *sql.DB, query *Query, writer DataWriter, opts ...Option) error {
+	return query.dump(ctx, sqlx.NewDb(db, "mysql"), writer, newDumpOptions(opts))
+}
+
+// DryRun prints DDL, queries with bound values and names of output files into out without writing result.
+// DDL is not printed if db is nil.
+func DryRun(ctx context.Context, db *sql.DB, query *Query, writer DataWriter, out io.Writer, opts ...Option) error {
+	var sqlxDB *sqlx.DB
+	if db != nil {
+		sqlxDB = sqlx.NewDb(db, "mysql")
+	}
+	return query.dryRun(ctx, sqlxDB, writer, newDumpOptions(opts).combined, out)
+}
+
+// Estimate runs EXPLAIN for every query which would be used for dumping
+func Estimate(ctx context.Context, db *sql.DB, query *Query, options *EstimateOptions) ([]*QueryEstimate, error) {
+	return query.estimate(ctx, sqlx.NewDb(db, "mysql"), options)
+}
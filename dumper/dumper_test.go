@@ -0,0 +1,120 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	query, err := NewQueryBuilder().Table("some_table", "id").Interval(1, 2).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	fw := NewMemoryFileWriter()
+	err = Dump(context.Background(), mockDB, query, NewSqlWriter(fw, "result.sql", ""), WithJobs(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+	assert.Contains(t, fw.Contents("result.sql"), "INSERT INTO `some_table` (`id`) VALUES (1);\n")
+	assert.Contains(t, fw.Contents("result.sql"), "INSERT INTO `some_table` (`id`) VALUES (2);\n")
+}
+
+func TestDumpWithLogger(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	query, err := NewQueryBuilder().Table("some_table", "id").Interval(1, 2).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	out := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	err = Dump(context.Background(), mockDB, query, NewSqlWriter(NewMemoryFileWriter(), "result.sql", ""), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	logs := out.String()
+	assert.Contains(t, logs, "msg=\"Dumping is started\"")
+	assert.Contains(t, logs, "msg=\"Query finished\"")
+	assert.Contains(t, logs, "msg=\"Rows are written into file\" table=some_table file=result.sql rows=1")
+}
+
+func TestDryRunWithoutDB(t *testing.T) {
+	query, err := NewQueryBuilder().Table("some_table", "id").Interval(1, 2).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	out := &bytes.Buffer{}
+	err = DryRun(context.Background(), nil, query, &SimpleWriter{}, out, WithCombined())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Contains(t, out.String(), "-- Query for combined rows into stdout\n")
+}
+
+func TestNewDumpOptions(t *testing.T) {
+	rules := []*MaskingRule{}
+	checkpoint := &Checkpoint{}
+	fw := NewMemoryFileWriter()
+	progress := NewProgressReporter(&bytes.Buffer{}, false, time.Second)
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	options := newDumpOptions([]Option{
+		WithCombined(),
+		WithJobs(4),
+		WithQueryTimeout(time.Minute),
+		WithChunkSize(100),
+		WithDedupSpill(10, "/tmp"),
+		WithMasking(rules, "secret"),
+		WithCheckpoint(checkpoint, fw),
+		WithProgress(progress),
+		WithLogger(logger),
+	})
+	assert.True(t, options.combined)
+	assert.Equal(t, 4, options.jobs)
+	assert.Equal(t, time.Minute, options.queryTimeout)
+	assert.Equal(t, int64(100), options.chunkSize)
+	assert.Equal(t, 10, options.dedupSpillLimit)
+	assert.Equal(t, "/tmp", options.dedupSpillDir)
+	assert.Equal(t, rules, options.maskingRules)
+	assert.Equal(t, "secret", options.maskingSecret)
+	assert.Equal(t, checkpoint, options.checkpoint)
+	assert.Equal(t, fw, options.fileWriter)
+	assert.Equal(t, progress, options.progress)
+	assert.Equal(t, logger, options.logger)
+
+	assert.Equal(t, 1, newDumpOptions(nil).jobs)
+	assert.Equal(t, discardLogger, newDumpOptions(nil).logger)
+}
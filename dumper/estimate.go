@@ -0,0 +1,172 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// EstimateOptions contains settings of estimate command
+type EstimateOptions struct {
+	Combined     bool
+	Count        bool
+	MaxRows      int64
+	MaxFullScans int
+}
+
+// QueryEstimate contains result of EXPLAIN for query of one table
+type QueryEstimate struct {
+	Table     string
+	Rows      int64
+	Count     int64
+	Keys      []string
+	FullScans []string
+}
+
+// ExplainRow represents result row from EXPLAIN command
+type ExplainRow struct {
+	table string
+	scan  string
+	key   string
+	rows  int64
+}
+
+// estimate runs EXPLAIN for every query which would be used for dumping
+func (q *Query) estimate(ctx context.Context, db *sqlx.DB, options *EstimateOptions) (estimates []*QueryEstimate, err error) {
+	if len(q.primaryInterval) != 2 {
+		return nil, fmt.Errorf("primaryInterval should contain two values")
+	}
+	estimates = make([]*QueryEstimate, 0)
+	if options.Combined {
+		estimate, err := estimateQuery(ctx, db, "combined", q.toSqlForCombinedRows(), q.primaryInterval, options.Count)
+		if err != nil {
+			return nil, err
+		}
+		return append(estimates, estimate), nil
+	}
+	queries, err := q.toSqlForTables()
+	if err != nil {
+		return nil, err
+	}
+	for i, qt := range q.tables {
+		estimate, err := estimateQuery(ctx, db, qt.ref(), queries[i], q.primaryInterval, options.Count)
+		if err != nil {
+			return nil, err
+		}
+		estimates = append(estimates, estimate)
+	}
+	return estimates, nil
+}
+
+func estimateQuery(ctx context.Context, db *sqlx.DB, tableName string, query string, interval []int64, count bool) (estimate *QueryEstimate, err error) {
+	explainRows, err := explainQuery(ctx, db, query, interval[0], interval[1])
+	if err != nil {
+		return nil, err
+	}
+	estimate = &QueryEstimate{
+		Table:     tableName,
+		Count:     -1,
+		Keys:      make([]string, 0),
+		FullScans: make([]string, 0),
+	}
+	for _, explainRow := range explainRows {
+		estimate.Rows += explainRow.rows
+		if explainRow.key != "" && !contains(estimate.Keys, explainRow.key) {
+			estimate.Keys = append(estimate.Keys, explainRow.key)
+		}
+		if explainRow.scan == "ALL" {
+			estimate.FullScans = append(estimate.FullScans, explainRow.table)
+		}
+	}
+	if count {
+		resultsMaps, err := dbSelect(ctx, db, "SELECT COUNT(*) AS `count` FROM (\n"+query+"\n) AS `estimated`", interval[0], interval[1])
+		if err != nil {
+			return nil, err
+		}
+		if len(resultsMaps) > 0 {
+			estimate.Count = explainValueToInt((*resultsMaps[0])["count"])
+		}
+	}
+	return estimate, nil
+}
+
+func explainQuery(ctx context.Context, db *sqlx.DB, query string, args ...interface{}) (explainRows []*ExplainRow, err error) {
+	resultsMaps, err := dbSelect(ctx, db, "EXPLAIN "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	explainRows = make([]*ExplainRow, 0)
+	for _, result := range resultsMaps {
+		explainRows = append(explainRows, &ExplainRow{
+			table: explainValueToString((*result)["table"]),
+			scan:  explainValueToString((*result)["type"]),
+			key:   explainValueToString((*result)["key"]),
+			rows:  explainValueToInt((*result)["rows"]),
+		})
+	}
+	return explainRows, nil
+}
+
+// CheckEstimates returns error if estimates exceed limits. Zero limit means no limit.
+func CheckEstimates(estimates []*QueryEstimate, options *EstimateOptions) error {
+	fullScans := 0
+	for _, estimate := range estimates {
+		if options.MaxRows > 0 && estimate.Rows > options.MaxRows {
+			return fmt.Errorf("Estimated rows for table '%s' %d exceed limit %d", estimate.Table, estimate.Rows, options.MaxRows)
+		}
+		if options.MaxRows > 0 && estimate.Count > options.MaxRows {
+			return fmt.Errorf("Count of rows for table '%s' %d exceeds limit %d", estimate.Table, estimate.Count, options.MaxRows)
+		}
+		fullScans += len(estimate.FullScans)
+	}
+	if options.MaxFullScans > 0 && fullScans > options.MaxFullScans {
+		return fmt.Errorf("Found %d full scans which exceed limit %d", fullScans, options.MaxFullScans)
+	}
+	return nil
+}
+
+// PrintEstimates prints estimates as table
+func PrintEstimates(out io.Writer, estimates []*QueryEstimate) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tEST. ROWS\tCOUNT\tKEYS\tWARNINGS")
+	for _, estimate := range estimates {
+		count := "-"
+		if estimate.Count >= 0 {
+			count = strconv.FormatInt(estimate.Count, 10)
+		}
+		keys := strings.Join(estimate.Keys, ",")
+		if keys == "" {
+			keys = "-"
+		}
+		warnings := make([]string, 0)
+		for _, table := range estimate.FullScans {
+			warnings = append(warnings, "full scan of "+table)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", estimate.Table, estimate.Rows, count, keys, strings.Join(warnings, ", "))
+	}
+	tw.Flush()
+}
+
+func explainValueToString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return maskedValueToString(v)
+}
+
+func explainValueToInt(v interface{}) int64 {
+	switch typedValue := v.(type) {
+	case int:
+		return int64(typedValue)
+	case int64:
+		return typedValue
+	case float64:
+		return int64(typedValue)
+	}
+	value, _ := strconv.ParseInt(explainValueToString(v), 10, 64)
+	return value
+}
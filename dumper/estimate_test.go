@@ -0,0 +1,127 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"strings"
+	"testing"
+)
+
+var explainColumns = []string{"id", "select_type", "table", "type", "possible_keys", "key", "rows", "Extra"}
+
+func TestQueryEstimate(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("EXPLAIN SELECT (.+) FROM `routes` WHERE `routes`.`id` BETWEEN \\? AND \\?").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows(explainColumns).
+			AddRow(1, "SIMPLE", "routes", "range", "PRIMARY", "PRIMARY", []uint8("3"), "Using where"))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) AS `count` FROM \\( SELECT (.+) FROM `routes` (.+) \\) AS `estimated`").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(3)))
+
+	mock.ExpectQuery("EXPLAIN SELECT (.+) FROM `stations` WHERE (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows(explainColumns).
+			AddRow(1, "PRIMARY", "stations", "ALL", nil, nil, int64(100), "Using where").
+			AddRow(2, "SUBQUERY", "routes", "range", "PRIMARY", "PRIMARY", int64(3), "Using where"))
+	mock.ExpectQuery("SELECT COUNT(.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(7)))
+
+	mock.ExpectQuery("EXPLAIN SELECT (.+) FROM `stations_for_routes` WHERE (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows(explainColumns).
+			AddRow(1, "PRIMARY", "stations_for_routes", "ref", "route_id", "route_id", int64(5), ""))
+	mock.ExpectQuery("SELECT COUNT(.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(5)))
+
+	estimates, err := typicalQuery.estimate(context.Background(), sqlxDB, &EstimateOptions{Count: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+
+	out := &bytes.Buffer{}
+	PrintEstimates(out, estimates)
+	expected := "TABLE                EST. ROWS  COUNT  KEYS      WARNINGS\n" +
+		"routes               3          3      PRIMARY   \n" +
+		"stations             103        7      PRIMARY   full scan of stations\n" +
+		"stations_for_routes  5          5      route_id  \n"
+	if out.String() != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, out.String())
+	}
+
+	err = CheckEstimates(estimates, &EstimateOptions{MaxRows: 100})
+	if err == nil || !strings.Contains(err.Error(), "stations") {
+		t.Errorf("Expected error about rows of stations, got %v", err)
+	}
+	err = CheckEstimates(estimates, &EstimateOptions{MaxRows: 6})
+	if err == nil {
+		t.Errorf("Expected error about counted rows, got nil")
+	}
+	err = CheckEstimates(estimates, &EstimateOptions{MaxFullScans: 0})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	err = CheckEstimates([]*QueryEstimate{
+		{Table: "a", FullScans: []string{"a"}},
+		{Table: "b", FullScans: []string{"b"}},
+	}, &EstimateOptions{MaxFullScans: 1})
+	if err == nil {
+		t.Errorf("Expected error about full scans, got nil")
+	}
+}
+
+func TestQueryEstimateCombined(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("EXPLAIN SELECT (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows(explainColumns).
+			AddRow(1, "SIMPLE", "routes", "range", "PRIMARY", "PRIMARY", int64(3), ""))
+
+	estimates, err := typicalQuery.estimate(context.Background(), sqlxDB, &EstimateOptions{Combined: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	if len(estimates) != 1 || estimates[0].Table != "combined" || estimates[0].Count != -1 {
+		t.Errorf("Unexpected estimates: %+v", estimates)
+	}
+}
+
+func TestQueryEstimateErrors(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("EXPLAIN (.+)").WillReturnError(fmt.Errorf("Some error"))
+	_, err = typicalQuery.estimate(context.Background(), sqlxDB, &EstimateOptions{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+
+	emptyIntervalQuery := &Query{tables: typicalQuery.tables, relations: typicalQuery.relations}
+	_, err = emptyIntervalQuery.estimate(context.Background(), sqlxDB, &EstimateOptions{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
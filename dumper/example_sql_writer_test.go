@@ -1,9 +1,12 @@
-package main
+package dumper
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 func ExampleSqlWriter_WriteRows() {
-	fw := NewTestFileWriter()
+	fw := NewMemoryFileWriter()
 	writer := NewSqlWriter(fw, "result.sql", "")
 	rows := make([]*map[string]interface{}, 0)
 	rows = append(rows, &map[string]interface{}{
@@ -27,8 +30,8 @@ func ExampleSqlWriter_WriteRows() {
 		"strange": uintptr(1),
 	})
 
-	writer.WriteRows("some_table", []string{"name", "title", "id", "value", "amount", "chars", "nulled", "strange"}, rows)
-	fmt.Printf(fw.getContents("result.sql"))
+	writer.WriteRows(context.Background(), "some_table", []string{"name", "title", "id", "value", "amount", "chars", "nulled", "strange"}, rows)
+	fmt.Printf(fw.Contents("result.sql"))
 
 	// Output:
 	// INSERT INTO `some_table` (`name`, `title`, `id`, `value`, `amount`, `chars`, `nulled`, `strange`) VALUES ('one', 'two', 123, 456, 1.230000, '&#)', NULL, UNDEFINED);
@@ -36,7 +39,7 @@ func ExampleSqlWriter_WriteRows() {
 }
 
 func ExampleSqlWriter_WriteDDL() {
-	fw := NewTestFileWriter()
+	fw := NewMemoryFileWriter()
 	writer := NewSqlWriter(fw, "result.sql", "")
 	ddl := "CREATE TABLE `some_table` (\n"
 	ddl += "    `id` bigint(20) NOT NULL,\n"
@@ -44,8 +47,8 @@ func ExampleSqlWriter_WriteDDL() {
 	ddl += "    PRIMARY KEY (`id`),\n"
 	ddl += "    UNIQUE INDEX `name` (`name`)\n"
 	ddl += ");"
-	writer.WriteDDL("some_table", ddl)
-	fmt.Printf(fw.getContents("result.sql"))
+	writer.WriteDDL(context.Background(), "some_table", ddl)
+	fmt.Printf(fw.Contents("result.sql"))
 
 	// Output:
 	// SET FOREIGN_KEY_CHECKS=0;
@@ -59,18 +62,18 @@ func ExampleSqlWriter_WriteDDL() {
 }
 
 func ExampleSqlWriter_WriteDDL_toDir() {
-	fw := NewTestFileWriter()
+	fw := NewMemoryFileWriter()
 	writer := NewSqlWriter(fw, "", "/tmp/some_dir")
 	ddl1 := "CREATE TABLE `some_table1` (\n"
 	ddl1 += "    `id` bigint(20) NOT NULL\n"
 	ddl1 += ");"
-	writer.WriteDDL("some_table1", ddl1)
+	writer.WriteDDL(context.Background(), "some_table1", ddl1)
 	ddl2 := "CREATE TABLE `some_table2` (\n"
 	ddl2 += "    `id` bigint(20) NOT NULL\n"
 	ddl2 += ");"
-	writer.WriteDDL("some_table2", ddl2)
-	fmt.Printf(fw.getContents("/tmp/some_dir/some_table1.sql"))
-	fmt.Printf(fw.getContents("/tmp/some_dir/some_table2.sql"))
+	writer.WriteDDL(context.Background(), "some_table2", ddl2)
+	fmt.Printf(fw.Contents("/tmp/some_dir/some_table1.sql"))
+	fmt.Printf(fw.Contents("/tmp/some_dir/some_table2.sql"))
 
 	// Output:
 	// SET FOREIGN_KEY_CHECKS=0;
@@ -90,7 +93,7 @@ func ExampleSqlWriter_WriteRows_fileWriteError() {
 	writer := NewSqlWriter(fw, "result.sql", "")
 	rows := make([]*map[string]interface{}, 0)
 	rows = append(rows, &map[string]interface{}{"name": "one"})
-	err := writer.WriteRows("some_table", []string{"name"}, rows)
+	err := writer.WriteRows(context.Background(), "some_table", []string{"name"}, rows)
 	fmt.Print(err)
 
 	// Output:
@@ -102,11 +105,11 @@ func ExampleSqlWriter_WriteRows_fileHandlerError() {
 	writer := NewSqlWriter(fw, "result.sql", "")
 	rows := make([]*map[string]interface{}, 0)
 	rows = append(rows, &map[string]interface{}{"name": "one"})
-	err := writer.WriteRows("some_table", []string{"name"}, rows)
+	err := writer.WriteRows(context.Background(), "some_table", []string{"name"}, rows)
 	fmt.Print(err)
 
 	// Output:
-	// Some testing error at getFileHandler
+	// Some testing error at GetFileHandler
 }
 
 func ExampleSqlWriter_WriteDDL_fileWriteError() {
@@ -115,7 +118,7 @@ func ExampleSqlWriter_WriteDDL_fileWriteError() {
 	ddl := "CREATE TABLE `some_table` (\n"
 	ddl += "    `id` bigint(20) NOT NULL\n"
 	ddl += ");"
-	err := writer.WriteDDL("some_table", ddl)
+	err := writer.WriteDDL(context.Background(), "some_table", ddl)
 	fmt.Print(err)
 
 	// Output:
@@ -128,9 +131,9 @@ func ExampleSqlWriter_WriteDDL_fileHandlerError() {
 	ddl := "CREATE TABLE `some_table` (\n"
 	ddl += "    `id` bigint(20) NOT NULL\n"
 	ddl += ");"
-	err := writer.WriteDDL("some_table", ddl)
+	err := writer.WriteDDL(context.Background(), "some_table", ddl)
 	fmt.Print(err)
 
 	// Output:
-	// Some testing error at getFileHandler
+	// Some testing error at GetFileHandler
 }
@@ -1,4 +1,6 @@
-package main
+package dumper
+
+import "context"
 
 func ExampleSimpleWriter_WriteRows() {
 	writer := &SimpleWriter{}
@@ -11,7 +13,7 @@ func ExampleSimpleWriter_WriteRows() {
 	rows = append(rows, &map[string]interface{}{"nulled": nil})
 	rows = append(rows, &map[string]interface{}{"strange": uintptr(1)})
 
-	writer.WriteRows("some_table", []string{}, rows)
+	writer.WriteRows(context.Background(), "some_table", []string{}, rows)
 
 	// Output:
 	// some_table
@@ -32,7 +34,7 @@ func ExampleSimpleWriter_WriteDDL() {
 	ddl += "    PRIMARY KEY (`id`),\n"
 	ddl += "    UNIQUE INDEX `name` (`name`)\n"
 	ddl += ");"
-	writer.WriteDDL("some_table", ddl)
+	writer.WriteDDL(context.Background(), "some_table", ddl)
 
 	// Output:
 	// CREATE TABLE `some_table` (
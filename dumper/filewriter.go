@@ -0,0 +1,445 @@
+package dumper
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// File is interface which can writes strings to file
+type File interface {
+	WriteString(s string) (n int, err error)
+	Close() error
+}
+
+// FileWriter is interface which can instantiate File.
+// Custom file writers can implement optional interfaces to support more features:
+// ResumableFileWriter and DirectFileWriter for checkpoints, CompressedFileWriter for compression,
+// FileSizer for progress and PartialCleaner for cleanup after failure.
+type FileWriter interface {
+	GetFileHandler(filename string) (File, error)
+}
+
+// PartialCleaner is implemented by file writers which can clean up output of failed dump
+type PartialCleaner interface {
+	CleanupPartial(keepPartial bool) error
+}
+
+// FileSizer is implemented by file writers which know sizes of files which are being written, e.g. of temporary files
+type FileSizer interface {
+	FileSize(filename string) (int64, bool)
+}
+
+// FileWriterHolder is implemented by data writers which write files through FileWriter
+type FileWriterHolder interface {
+	FileWriter() FileWriter
+}
+
+// AppendedFile is implemented by files which can be appended to existing content, e.g. to skip CSV header
+type AppendedFile interface {
+	IsAppended() bool
+}
+
+// Stdout is name of file which means standard output, e.g. --file -
+const Stdout = "-"
+
+// WriteMode defines how OsFileWriter treats existing files
+type WriteMode int
+
+const (
+	// WriteCreate creates new files and fails if file exists. It is default mode.
+	WriteCreate WriteMode = iota
+	// WriteOverwrite replaces existing files
+	WriteOverwrite
+	// WriteAppend appends to existing files
+	WriteAppend
+)
+
+// FileWriterOption is setting of OsFileWriter
+type FileWriterOption func(fw *OsFileWriter)
+
+// WithCompression compresses files. Nil compression means plain files.
+func WithCompression(compression *Compression) FileWriterOption {
+	return func(fw *OsFileWriter) {
+		fw.compression = compression
+	}
+}
+
+// WithWriteMode sets how existing files are treated
+func WithWriteMode(mode WriteMode) FileWriterOption {
+	return func(fw *OsFileWriter) {
+		fw.mode = mode
+	}
+}
+
+// WithFileLogger sets logger for opening, renaming and cleanup of files. Logs are discarded by default.
+func WithFileLogger(logger *slog.Logger) FileWriterOption {
+	return func(fw *OsFileWriter) {
+		fw.logger = logger
+	}
+}
+
+// OsFileWriter writes files using filesystem and methods from OS. It is safe for concurrent use.
+// Files are kept open between writes and closed by Close, so compressed streams are not interrupted.
+// New and overwritten files are written into temporary files in the same directory which are renamed by Close,
+// so target file is never half-written.
+type OsFileWriter struct {
+	openFiles   map[string]*osFile
+	compression *Compression
+	mode        WriteMode
+	direct      bool
+	stdout      *os.File
+	logger      *slog.Logger
+	mutex       sync.Mutex
+}
+
+// osFile is file which is kept open by OsFileWriter
+type osFile struct {
+	file       *os.File
+	compressor io.WriteCloser
+	isStdout   bool
+	// tmpName is name of temporary file which is renamed to target by Close
+	tmpName string
+	// created is true if file did not exist before writing
+	created bool
+	// appended is true if data is appended to file which is not empty
+	appended bool
+	mutex    sync.Mutex
+}
+
+// NewOsFileWriter builds new OsFileWriter
+func NewOsFileWriter(options ...FileWriterOption) *OsFileWriter {
+	fw := &OsFileWriter{
+		openFiles: make(map[string]*osFile, 0),
+		stdout:    os.Stdout,
+		logger:    discardLogger,
+	}
+	for _, option := range options {
+		option(fw)
+	}
+	return fw
+}
+
+// Compression returns compression of files or nil for plain files
+func (fw *OsFileWriter) Compression() *Compression {
+	return fw.compression
+}
+
+// GetFileHandler creates new file or returns file which was created by this writer for appending.
+// Filename - means standard output.
+func (fw *OsFileWriter) GetFileHandler(filename string) (f File, err error) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	if openFile, ok := fw.openFiles[filename]; ok {
+		return openFile, nil
+	}
+	if filename == Stdout {
+		openFile, err := fw.newOsFile(fw.stdout)
+		if err != nil {
+			return nil, err
+		}
+		openFile.isStdout = true
+		fw.logger.Debug("Output is written into stdout")
+		fw.openFiles[filename] = openFile
+		return openFile, nil
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	exists := err == nil
+	if exists && fw.mode == WriteCreate {
+		return nil, fmt.Errorf("File '%s' already exists. Use --overwrite or --append", filename)
+	}
+	var file *os.File
+	tmpName := ""
+	switch {
+	case fw.mode == WriteAppend:
+		file, err = os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	case fw.direct:
+		file, err = os.Create(filename)
+	default:
+		file, err = ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+		if err == nil {
+			tmpName = file.Name()
+			err = file.Chmod(0644)
+		}
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+			os.Remove(tmpName)
+		}
+		return nil, err
+	}
+	openFile, err := fw.newOsFile(file)
+	if err != nil {
+		os.Remove(tmpName)
+		return nil, err
+	}
+	openFile.tmpName = tmpName
+	openFile.created = !exists
+	openFile.appended = fw.mode == WriteAppend && exists && info.Size() > 0
+	fw.logger.Debug("File is opened", "file", filename, "tmp_file", tmpName, "exists", exists)
+	fw.openFiles[filename] = openFile
+	return openFile, nil
+}
+
+func (fw *OsFileWriter) newOsFile(file *os.File) (*osFile, error) {
+	openFile := &osFile{file: file}
+	if fw.compression != nil {
+		compressor, err := fw.compression.NewWriter(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		openFile.compressor = compressor
+	}
+	return openFile, nil
+}
+
+// Close flushes compressed data, closes all files and renames temporary files to target files
+func (fw *OsFileWriter) Close() (err error) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	for filename, openFile := range fw.openFiles {
+		fileErr := openFile.close()
+		if fileErr == nil && openFile.tmpName != "" {
+			fileErr = fw.commit(filename, openFile.tmpName)
+		}
+		if fileErr != nil {
+			err = fmt.Errorf("Fail to close file '%s': %s", filename, fileErr)
+			continue
+		}
+		delete(fw.openFiles, filename)
+	}
+	return err
+}
+
+// commit renames temporary file to target file
+func (fw *OsFileWriter) commit(filename string, tmpName string) error {
+	if _, err := os.Stat(filename); fw.mode == WriteCreate && !os.IsNotExist(err) {
+		return fmt.Errorf("File '%s' was created by someone else during dump", filename)
+	}
+	err := os.Rename(tmpName, filename)
+	if err != nil {
+		return err
+	}
+	fw.logger.Debug("Temporary file is renamed", "file", filename, "tmp_file", tmpName)
+	return nil
+}
+
+// FileSize returns size of file which is being written, e.g. of temporary file
+func (fw *OsFileWriter) FileSize(filename string) (int64, bool) {
+	fw.mutex.Lock()
+	openFile, ok := fw.openFiles[filename]
+	fw.mutex.Unlock()
+	if !ok || openFile.tmpName == "" {
+		return 0, false
+	}
+	size, err := getFileSize(openFile.tmpName)
+	return size, err == nil
+}
+
+// writtenFileSize returns size of output file of writer. Output which is not renamed yet is measured in temporary file.
+func writtenFileSize(writer DataWriter, filename string) int64 {
+	if holder, ok := writer.(FileWriterHolder); ok {
+		if sizer, ok := holder.FileWriter().(FileSizer); ok {
+			if size, ok := sizer.FileSize(filename); ok {
+				return size
+			}
+		}
+	}
+	size, _ := getFileSize(filename)
+	return size
+}
+
+// WriteDirectly disables temporary files, so sizes of target files can be saved into checkpoint
+func (fw *OsFileWriter) WriteDirectly() {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	fw.direct = true
+}
+
+// WriteString writes string into file through compressor if file is compressed
+func (f *osFile) WriteString(s string) (n int, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.compressor != nil {
+		return f.compressor.Write([]byte(s))
+	}
+	return f.file.WriteString(s)
+}
+
+// IsAppended returns true if file had content before writing
+func (f *osFile) IsAppended() bool {
+	return f.appended
+}
+
+// Close is called by writers after every write. File stays open until Close of OsFileWriter.
+func (f *osFile) Close() error {
+	return nil
+}
+
+func (f *osFile) close() (err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	if f.compressor != nil {
+		err = f.compressor.Close()
+	}
+	if !f.isStdout {
+		if fileErr := f.file.Close(); err == nil {
+			err = fileErr
+		}
+	}
+	f.file = nil
+	return err
+}
+
+// CleanupPartial removes temporary and created files or renames them with suffix .partial when keepPartial is set.
+// Files which existed before appending are kept.
+func (fw *OsFileWriter) CleanupPartial(keepPartial bool) (err error) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	for filename, openFile := range fw.openFiles {
+		fileErr := openFile.close()
+		delete(fw.openFiles, filename)
+		if openFile.isStdout {
+			continue
+		}
+		writtenName := filename
+		if openFile.tmpName != "" {
+			writtenName = openFile.tmpName
+		} else if !openFile.created {
+			fw.logger.Warn("Partial output is kept in existing file", "file", filename)
+			continue
+		}
+		if keepPartial {
+			if renameErr := os.Rename(writtenName, filename+".partial"); renameErr != nil {
+				fileErr = renameErr
+			}
+			fw.logger.Info("Partial file is renamed", "file", filename+".partial")
+		} else {
+			if removeErr := os.Remove(writtenName); removeErr != nil {
+				fileErr = removeErr
+			}
+			fw.logger.Info("Partial file is removed", "file", writtenName)
+		}
+		if fileErr != nil {
+			err = fileErr
+		}
+	}
+	return err
+}
+
+// ResumeFile truncates existing file to size and opens it for appending. Missing file is created.
+func (fw *OsFileWriter) ResumeFile(filename string, size int64) error {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	if fw.compression != nil {
+		return fmt.Errorf("Compressed file '%s' can not be resumed", filename)
+	}
+	if filename == Stdout {
+		return fmt.Errorf("Output into stdout can not be resumed")
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if info.Size() < size {
+		f.Close()
+		return fmt.Errorf("File '%s' is shorter than recorded in checkpoint: %d < %d bytes", filename, info.Size(), size)
+	}
+	err = f.Truncate(size)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	fw.logger.Info("File is truncated to continue writing", "file", filename, "size", size)
+	fw.openFiles[filename] = &osFile{file: f, created: size == 0}
+	return nil
+}
+
+// MemoryFileWriter keeps files in memory, e.g. for tests or for processing of small dumps in the same process.
+// It is safe for concurrent use.
+type MemoryFileWriter struct {
+	files map[string]*memoryFile
+	mutex sync.Mutex
+}
+
+// memoryFile is file of MemoryFileWriter
+type memoryFile struct {
+	contents strings.Builder
+	mutex    *sync.Mutex
+}
+
+// NewMemoryFileWriter builds new MemoryFileWriter
+func NewMemoryFileWriter() *MemoryFileWriter {
+	return &MemoryFileWriter{
+		files: make(map[string]*memoryFile),
+	}
+}
+
+// GetFileHandler creates new file or returns file which was created before for appending
+func (fw *MemoryFileWriter) GetFileHandler(filename string) (File, error) {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	if file, ok := fw.files[filename]; ok {
+		return file, nil
+	}
+	file := &memoryFile{mutex: &fw.mutex}
+	fw.files[filename] = file
+	return file, nil
+}
+
+// Contents returns contents of file or empty string if file was not created
+func (fw *MemoryFileWriter) Contents(filename string) string {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	if file, ok := fw.files[filename]; ok {
+		return file.contents.String()
+	}
+	return ""
+}
+
+// Filenames returns sorted names of created files
+func (fw *MemoryFileWriter) Filenames() []string {
+	fw.mutex.Lock()
+	defer fw.mutex.Unlock()
+	filenames := make([]string, 0, len(fw.files))
+	for filename := range fw.files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	return filenames
+}
+
+// WriteString appends string to contents of file
+func (f *memoryFile) WriteString(s string) (n int, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.contents.WriteString(s)
+}
+
+// Close does nothing, contents are kept until MemoryFileWriter is dropped
+func (f *memoryFile) Close() error {
+	return nil
+}
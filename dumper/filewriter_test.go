@@ -0,0 +1,405 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestErrorFile
+
+type TestErrorFile struct {
+}
+
+func (f *TestErrorFile) WriteString(s string) (n int, err error) {
+	return 0, fmt.Errorf("Some testing error at WriteString")
+}
+func (f *TestErrorFile) Close() error {
+	return nil
+}
+
+type TestFileErrorWriter struct {
+}
+
+func (fw *TestFileErrorWriter) GetFileHandler(filename string) (f File, err error) {
+	testFile := &TestErrorFile{}
+	return testFile, nil
+}
+
+type TestFileHandlerErrorWriter struct {
+}
+
+func (fw *TestFileHandlerErrorWriter) GetFileHandler(filename string) (f File, err error) {
+	return nil, fmt.Errorf("Some testing error at GetFileHandler")
+}
+
+// TestFileWhichFailsAtSecondAttempt
+
+type TestFileWhichFailsAtSecondAttempt struct {
+	attempt int
+}
+
+func (f *TestFileWhichFailsAtSecondAttempt) WriteString(s string) (n int, err error) {
+	f.attempt += 1
+	if f.attempt > 1 {
+		return 0, fmt.Errorf("Error at second attempt to write file")
+	}
+	return 1, nil
+}
+func (f *TestFileWhichFailsAtSecondAttempt) Close() error {
+	return nil
+}
+
+type TestFileWhichFailsAtSecondAttemptWriter struct {
+}
+
+func (fw *TestFileWhichFailsAtSecondAttemptWriter) GetFileHandler(filename string) (f File, err error) {
+	testFile := &TestFileWhichFailsAtSecondAttempt{}
+	return testFile, nil
+}
+
+// Tests on NewOsFileWriter
+
+func TestGetFileHandlerFileExists(t *testing.T) {
+	fw := NewOsFileWriter()
+	_, err := fw.GetFileHandler("query.go")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestGetFileHandlerDoubleAccess(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test_file_handler")
+	fw := NewOsFileWriter()
+	_, err := fw.GetFileHandler(filename)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	_, err = fw.GetFileHandler(filename)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	if err = fw.Close(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestGetFileHandlerStatError(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "file")
+	ioutil.WriteFile(filename, []byte("data"), 0644)
+	fw := NewOsFileWriter(WithWriteMode(WriteAppend))
+	_, err := fw.GetFileHandler(filepath.Join(filename, "test_file_handler"))
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestGetFileHandlerCreatingError(t *testing.T) {
+	fw := NewOsFileWriter()
+	_, err := fw.GetFileHandler("/not_writtable/file")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestCleanupPartial(t *testing.T) {
+	os.Remove("test_partial")
+	fw := NewOsFileWriter()
+	_, err := fw.GetFileHandler("test_partial")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	err = fw.CleanupPartial(false)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	if _, err = os.Stat("test_partial"); !os.IsNotExist(err) {
+		t.Errorf("Expected removed file, but got %v", err)
+		os.Remove("test_partial")
+	}
+}
+
+func TestCleanupPartialKeep(t *testing.T) {
+	os.Remove("test_partial")
+	os.Remove("test_partial.partial")
+	fw := NewOsFileWriter()
+	_, err := fw.GetFileHandler("test_partial")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	err = fw.CleanupPartial(true)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	if _, err = os.Stat("test_partial.partial"); err != nil {
+		t.Errorf("Expected renamed file, but got %v", err)
+	}
+	os.Remove("test_partial")
+	os.Remove("test_partial.partial")
+}
+
+func TestOsFileWriterKeepsFileOpen(t *testing.T) {
+	os.Remove("test_open_file")
+	defer os.Remove("test_open_file")
+	fw := NewOsFileWriter()
+	f, err := fw.GetFileHandler("test_open_file")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("first")
+	f.Close()
+	f, _ = fw.GetFileHandler("test_open_file")
+	f.WriteString(" second")
+	f.Close()
+
+	err = fw.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents, _ := ioutil.ReadFile("test_open_file")
+	if string(contents) != "first second" {
+		t.Errorf("Expected 'first second', but got '%s'", contents)
+	}
+	_, err = f.WriteString("third")
+	if err == nil {
+		t.Errorf("Expected error of closed file, but got nil")
+	}
+}
+
+// listTemporaryFiles returns temporary files of OsFileWriter in directory
+func listTemporaryFiles(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	return files
+}
+
+func TestOsFileWriterAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomic")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "result.sql")
+
+	fw := NewOsFileWriter()
+	writer := NewSqlWriter(fw, filename, "")
+	f, err := fw.GetFileHandler(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("first;\n")
+	if _, err = os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected no target file before Close, but got %v", err)
+	}
+	assert.Equal(t, 1, len(listTemporaryFiles(dir)))
+	assert.Equal(t, int64(7), writtenFileSize(writer, filename))
+
+	err = fw.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, "first;\n", string(contents))
+	assert.Equal(t, 0, len(listTemporaryFiles(dir)))
+	info, _ := os.Stat(filename)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+}
+
+func TestOsFileWriterCreatedDuringDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomic")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "result.sql")
+
+	fw := NewOsFileWriter()
+	f, err := fw.GetFileHandler(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("ours;\n")
+	ioutil.WriteFile(filename, []byte("theirs;\n"), 0644)
+	err = fw.Close()
+	if err == nil {
+		t.Errorf("Expected error of file created during dump, but got nil")
+	}
+	contents, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, "theirs;\n", string(contents))
+
+	err = fw.CleanupPartial(false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(listTemporaryFiles(dir)))
+}
+
+func TestOsFileWriterOverwrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overwrite")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "result.sql")
+	ioutil.WriteFile(filename, []byte("old;\n"), 0644)
+
+	fw := NewOsFileWriter(WithWriteMode(WriteOverwrite))
+	f, err := fw.GetFileHandler(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("new;\n")
+	contents, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, "old;\n", string(contents))
+
+	err = fw.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents, _ = ioutil.ReadFile(filename)
+	assert.Equal(t, "new;\n", string(contents))
+}
+
+func TestOsFileWriterOverwriteFailed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overwrite")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "result.sql")
+	ioutil.WriteFile(filename, []byte("old;\n"), 0644)
+
+	fw := NewOsFileWriter(WithWriteMode(WriteOverwrite))
+	f, err := fw.GetFileHandler(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("half")
+	err = fw.CleanupPartial(false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, "old;\n", string(contents))
+	assert.Equal(t, 0, len(listTemporaryFiles(dir)))
+}
+
+func TestOsFileWriterAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "append")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	existing := filepath.Join(dir, "existing.csv")
+	created := filepath.Join(dir, "created.csv")
+	ioutil.WriteFile(existing, []byte("\"id\"\r\n1\r\n"), 0644)
+
+	fw := NewOsFileWriter(WithWriteMode(WriteAppend))
+	writer := NewCsvWriter(fw, existing, "", ",")
+	err = writer.WriteRows(context.Background(), "routes", []string{"id"}, []*map[string]interface{}{{"id": 2}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err := fw.GetFileHandler(created)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("3\r\n")
+	assert.Equal(t, 0, len(listTemporaryFiles(dir)))
+
+	err = fw.CleanupPartial(false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents, _ := ioutil.ReadFile(existing)
+	assert.Equal(t, "\"id\"\r\n1\r\n2\r\n", string(contents))
+	if _, err = os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("Expected removed created file, but got %v", err)
+	}
+}
+
+func TestOsFileWriterWriteDirectly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "direct")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "result.sql")
+
+	fw := NewOsFileWriter()
+	fw.WriteDirectly()
+	f, err := fw.GetFileHandler(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("first;\n")
+	size, _ := getFileSize(filename)
+	assert.Equal(t, int64(7), size)
+	assert.Equal(t, 0, len(listTemporaryFiles(dir)))
+	assert.Nil(t, fw.Close())
+}
+
+func TestOsFileWriterStdout(t *testing.T) {
+	stdout, err := ioutil.TempFile("", "stdout")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.Remove(stdout.Name())
+	defer stdout.Close()
+
+	fw := NewOsFileWriter()
+	fw.stdout = stdout
+	writer := NewSqlWriter(fw, Stdout, "")
+	assert.Equal(t, Stdout, writer.Filename("routes"))
+	f, err := fw.GetFileHandler(Stdout)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("first;\n")
+	f.Close()
+	f, _ = fw.GetFileHandler(Stdout)
+	f.WriteString("second;\n")
+
+	err = fw.CleanupPartial(false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err = os.Stat(Stdout); !os.IsNotExist(err) {
+		t.Errorf("Expected no file with name of stdout, but got %v", err)
+	}
+	_, err = stdout.WriteString("stdout is not closed\n")
+	assert.Nil(t, err)
+	contents, _ := ioutil.ReadFile(stdout.Name())
+	assert.Equal(t, "first;\nsecond;\nstdout is not closed\n", string(contents))
+
+	assert.EqualError(t, fw.ResumeFile(Stdout, 0), "Output into stdout can not be resumed")
+}
+
+func TestMemoryFileWriter(t *testing.T) {
+	fw := NewMemoryFileWriter()
+	f, err := fw.GetFileHandler("b.sql")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f.WriteString("first;")
+	f.Close()
+	f, _ = fw.GetFileHandler("b.sql")
+	n, err := f.WriteString("second;")
+	assert.Nil(t, err)
+	assert.Equal(t, 7, n)
+	fw.GetFileHandler("a.sql")
+
+	assert.Equal(t, "first;second;", fw.Contents("b.sql"))
+	assert.Equal(t, "", fw.Contents("a.sql"))
+	assert.Equal(t, "", fw.Contents("missing.sql"))
+	assert.Equal(t, []string{"a.sql", "b.sql"}, fw.Filenames())
+}
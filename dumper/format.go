@@ -0,0 +1,119 @@
+package dumper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Format describes output format: how to build its writer and what it supports
+type Format struct {
+	// Name is used to choose format, e.g. in --format
+	Name string
+	// Extension of output files. Empty extension means that format writes into stdout.
+	Extension string
+	// DDL is true if format writes DDL of tables
+	DDL bool
+	// Combined is true if format can write rows of all tables selected with one query as one result
+	Combined bool
+	// Separated is true if format can write rows of every table separately
+	Separated bool
+	// Flags are settings of format which are passed into NewWriter
+	Flags []*FormatFlag
+	// NewWriter builds writer. Flags contain values for all flags of format.
+	NewWriter func(fw FileWriter, dstFile string, dstDir string, flags map[string]string) (DataWriter, error)
+}
+
+// FormatFlag is setting of format, e.g. --csv-delimiter
+type FormatFlag struct {
+	Name    string
+	Default string
+	Usage   string
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[string]*Format)
+)
+
+// RegisterFormat makes output format available by its name.
+// It panics if format is registered twice or does not support any mode.
+func RegisterFormat(format *Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if format == nil || format.NewWriter == nil {
+		panic("dumper: RegisterFormat format is nil or has no NewWriter")
+	}
+	if !format.Combined && !format.Separated {
+		panic("dumper: RegisterFormat format " + format.Name + " supports neither combined nor separated mode")
+	}
+	if _, dup := formats[format.Name]; dup {
+		panic("dumper: RegisterFormat called twice for format " + format.Name)
+	}
+	formats[format.Name] = format
+}
+
+// LookupFormat returns registered format by name
+func LookupFormat(name string) (*Format, error) {
+	formatsMu.RLock()
+	format, ok := formats[name]
+	formatsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown format '%s'. Available formats: %s", name, strings.Join(Formats(), ", "))
+	}
+	return format, nil
+}
+
+// Formats returns sorted names of registered formats
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Writer builds writer of format for destination and decides whether rows of all tables
+// should be selected with one query (combined mode). Flags which are not set get default values.
+func (f *Format) Writer(fw FileWriter, dstFile string, dstDir string, flags map[string]string) (writer DataWriter, combined bool, err error) {
+	if f.Extension == "" && (dstFile != "" || dstDir != "") {
+		return nil, false, fmt.Errorf("Format '%s' writes into stdout and does not support --file and --dir", f.Name)
+	}
+	if dstFile != "" && dstDir != "" {
+		return nil, false, fmt.Errorf("Flags --file and --dir can not be used together")
+	}
+	if dstDir != "" && !f.Separated {
+		return nil, false, fmt.Errorf("Format '%s' does not support separated files in --dir", f.Name)
+	}
+	values, err := f.flagValues(flags)
+	if err != nil {
+		return nil, false, err
+	}
+	if f.Extension != "" && dstFile == "" && dstDir == "" {
+		dstFile = "result." + f.Extension
+	}
+	combined = f.Combined && dstDir == ""
+	writer, err = f.NewWriter(fw, dstFile, dstDir, values)
+	if err != nil {
+		return nil, false, err
+	}
+	return writer, combined, nil
+}
+
+func (f *Format) flagValues(flags map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(f.Flags))
+	for _, flag := range f.Flags {
+		values[flag.Name] = flag.Default
+	}
+	for name, value := range flags {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("Flag --%s is not supported by format '%s'", name, f.Name)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
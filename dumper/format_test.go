@@ -0,0 +1,135 @@
+package dumper
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func getFormatWriter(t *testing.T, name string, dstFile string, dstDir string, flags map[string]string) (DataWriter, bool, *MemoryFileWriter) {
+	format, err := LookupFormat(name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	fw := NewMemoryFileWriter()
+	writer, combined, err := format.Writer(fw, dstFile, dstDir, flags)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return writer, combined, fw
+}
+
+func TestFormatWriter_sqlDefault(t *testing.T) {
+	writer, combined, fw := getFormatWriter(t, "sql", "", "", nil)
+	assert.IsType(t, &SqlWriter{}, writer)
+	sqlWriter := writer.(*SqlWriter)
+	assert.Equal(t, fw, sqlWriter.fw)
+	assert.Equal(t, "result.sql", sqlWriter.dstFile)
+	assert.Equal(t, "", sqlWriter.dstDir)
+	assert.False(t, combined)
+}
+
+func TestFormatWriter_sqlDstFile(t *testing.T) {
+	writer, combined, _ := getFormatWriter(t, "sql", "custom.sql", "", nil)
+	sqlWriter := writer.(*SqlWriter)
+	assert.Equal(t, "custom.sql", sqlWriter.dstFile)
+	assert.Equal(t, "", sqlWriter.dstDir)
+	assert.False(t, combined)
+}
+
+func TestFormatWriter_sqlDstDir(t *testing.T) {
+	writer, combined, _ := getFormatWriter(t, "sql", "", "./", nil)
+	sqlWriter := writer.(*SqlWriter)
+	assert.Equal(t, "", sqlWriter.dstFile)
+	assert.Equal(t, "./", sqlWriter.dstDir)
+	assert.False(t, combined)
+}
+
+func TestFormatWriter_csvDefault(t *testing.T) {
+	writer, combined, fw := getFormatWriter(t, "csv", "", "", nil)
+	assert.IsType(t, &CsvWriter{}, writer)
+	csvWriter := writer.(*CsvWriter)
+	assert.Equal(t, fw, csvWriter.fw)
+	assert.Equal(t, "result.csv", csvWriter.dstFile)
+	assert.Equal(t, "", csvWriter.dstDir)
+	assert.Equal(t, ",", csvWriter.delimiter)
+	assert.True(t, combined)
+}
+
+func TestFormatWriter_csvDstFile(t *testing.T) {
+	writer, combined, _ := getFormatWriter(t, "csv", "custom.csv", "", map[string]string{"csv-delimiter": ";"})
+	csvWriter := writer.(*CsvWriter)
+	assert.Equal(t, "custom.csv", csvWriter.dstFile)
+	assert.Equal(t, "", csvWriter.dstDir)
+	assert.Equal(t, ";", csvWriter.delimiter)
+	assert.True(t, combined)
+}
+
+func TestFormatWriter_csvDstDir(t *testing.T) {
+	writer, combined, _ := getFormatWriter(t, "csv", "", "./", nil)
+	csvWriter := writer.(*CsvWriter)
+	assert.Equal(t, "", csvWriter.dstFile)
+	assert.Equal(t, "./", csvWriter.dstDir)
+	assert.False(t, combined)
+}
+
+func TestFormatWriter_simple(t *testing.T) {
+	writer, combined, _ := getFormatWriter(t, "simple", "", "", nil)
+	assert.IsType(t, &SimpleWriter{}, writer)
+	assert.True(t, combined)
+}
+
+func TestFormatWriterErrors(t *testing.T) {
+	sqlFormat, _ := LookupFormat("sql")
+	csvFormat, _ := LookupFormat("csv")
+	simpleFormat, _ := LookupFormat("simple")
+	fw := NewMemoryFileWriter()
+
+	_, _, err := simpleFormat.Writer(fw, "result.txt", "", nil)
+	assert.EqualError(t, err, "Format 'simple' writes into stdout and does not support --file and --dir")
+	_, _, err = sqlFormat.Writer(fw, "result.sql", "./", nil)
+	assert.EqualError(t, err, "Flags --file and --dir can not be used together")
+	_, _, err = sqlFormat.Writer(fw, "", "", map[string]string{"csv-delimiter": ";"})
+	assert.EqualError(t, err, "Flag --csv-delimiter is not supported by format 'sql'")
+	_, _, err = csvFormat.Writer(fw, "", "", map[string]string{"csv-delimiter": ""})
+	assert.EqualError(t, err, "Delimiter for csv format should not be empty")
+
+	combinedOnly := &Format{Name: "combined_only", Extension: "txt", Combined: true, NewWriter: sqlFormat.NewWriter}
+	_, _, err = combinedOnly.Writer(fw, "", "./", nil)
+	assert.EqualError(t, err, "Format 'combined_only' does not support separated files in --dir")
+}
+
+func TestRegisterFormat(t *testing.T) {
+	newWriter := func(fw FileWriter, dstFile string, dstDir string, flags map[string]string) (DataWriter, error) {
+		return &EmptyWriter{}, nil
+	}
+	RegisterFormat(&Format{Name: "test_empty", Combined: true, NewWriter: newWriter})
+	defer func() {
+		formatsMu.Lock()
+		delete(formats, "test_empty")
+		formatsMu.Unlock()
+	}()
+
+	format, err := LookupFormat("test_empty")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	writer, combined, err := format.Writer(NewMemoryFileWriter(), "", "", nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &EmptyWriter{}, writer)
+	assert.True(t, combined)
+	assert.NoError(t, writer.WriteDDL(context.Background(), "t", ""))
+
+	assert.Panics(t, func() {
+		RegisterFormat(&Format{Name: "test_empty", Combined: true, NewWriter: newWriter})
+	})
+	assert.Panics(t, func() {
+		RegisterFormat(&Format{Name: "test_no_modes", NewWriter: newWriter})
+	})
+	assert.Panics(t, func() {
+		RegisterFormat(&Format{Name: "test_no_writer", Combined: true})
+	})
+
+	_, err = LookupFormat("xml")
+	assert.EqualError(t, err, "Unknown format 'xml'. Available formats: csv, simple, sql, test_empty")
+}
@@ -0,0 +1,235 @@
+package dumper
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Graph contains tables of query and relations between them
+type Graph struct {
+	Tables    []*GraphTable
+	Relations []*GraphRelation
+	Interval  []int64
+}
+
+// GraphTable is node of graph. Tables which are not selected are known only from discovered foreign keys.
+type GraphTable struct {
+	Ref       string
+	Name      string
+	Columns   []string
+	Driving   bool
+	Selected  bool
+	Reachable bool
+}
+
+// GraphRelation is edge of graph. Discovered relations are foreign keys which are not used by query.
+type GraphRelation struct {
+	Table1     string
+	Column1    string
+	Table2     string
+	Column2    string
+	Discovered bool
+}
+
+// NewGraph builds graph of query. Foreign keys which touch selected tables are added as discovered relations.
+// Reachability is checked by relations of query only, because only they are used for dumping.
+func NewGraph(query *Query, foreignKeys []*ForeignKey) *Graph {
+	graph := &Graph{
+		Tables:    make([]*GraphTable, 0),
+		Relations: make([]*GraphRelation, 0),
+		Interval:  query.primaryInterval,
+	}
+	for i, qt := range query.tables {
+		graph.Tables = append(graph.Tables, &GraphTable{qt.ref(), qt.fullName(), qt.columns, i == 0, true, false})
+	}
+	for _, qr := range query.relations {
+		graph.Relations = append(graph.Relations, &GraphRelation{qr.table1, qr.column1, qr.table2, qr.column2, false})
+	}
+	graph.markReachable()
+	for _, fk := range foreignKeys {
+		graph.addForeignKey(fk)
+	}
+	return graph
+}
+
+// Unreachable returns selected tables which are not connected with the driving table by relations
+func (g *Graph) Unreachable() []string {
+	unreachable := make([]string, 0)
+	for _, table := range g.Tables {
+		if table.Selected && !table.Reachable {
+			unreachable = append(unreachable, table.Ref)
+		}
+	}
+	return unreachable
+}
+
+func (g *Graph) markReachable() {
+	if len(g.Tables) == 0 {
+		return
+	}
+	reached := map[string]bool{g.Tables[0].Ref: true}
+	for changed := true; changed; {
+		changed = false
+		for _, relation := range g.Relations {
+			if reached[relation.Table1] != reached[relation.Table2] {
+				reached[relation.Table1] = true
+				reached[relation.Table2] = true
+				changed = true
+			}
+		}
+	}
+	for _, table := range g.Tables {
+		table.Reachable = reached[table.Ref]
+	}
+}
+
+func (g *Graph) addForeignKey(fk *ForeignKey) {
+	refs := g.selectedTableRefs(fk.Table)
+	referencedRefs := g.selectedTableRefs(fk.ReferencedTable)
+	if len(refs) == 0 && len(referencedRefs) == 0 {
+		return
+	}
+	if len(refs) == 0 {
+		refs = []string{g.addNotSelectedTable(fk.Table)}
+	}
+	if len(referencedRefs) == 0 {
+		referencedRefs = []string{g.addNotSelectedTable(fk.ReferencedTable)}
+	}
+	for _, ref := range refs {
+		for _, referencedRef := range referencedRefs {
+			if !g.hasRelation(referencedRef, fk.ReferencedColumn, ref, fk.Column) {
+				g.Relations = append(g.Relations, &GraphRelation{referencedRef, fk.ReferencedColumn, ref, fk.Column, true})
+			}
+		}
+	}
+}
+
+// selectedTableRefs returns references of all selected tables with name
+func (g *Graph) selectedTableRefs(name string) []string {
+	refs := make([]string, 0)
+	for _, table := range g.Tables {
+		if table.Selected && table.Name == name {
+			refs = append(refs, table.Ref)
+		}
+	}
+	return refs
+}
+
+func (g *Graph) addNotSelectedTable(name string) string {
+	for _, table := range g.Tables {
+		if !table.Selected && table.Name == name {
+			return name
+		}
+	}
+	g.Tables = append(g.Tables, &GraphTable{name, name, []string{}, false, false, false})
+	return name
+}
+
+func (g *Graph) hasRelation(table1 string, column1 string, table2 string, column2 string) bool {
+	for _, relation := range g.Relations {
+		if relation.Table1 == table1 && relation.Column1 == column1 && relation.Table2 == table2 && relation.Column2 == column2 {
+			return true
+		}
+		if relation.Table1 == table2 && relation.Column1 == column2 && relation.Table2 == table1 && relation.Column2 == column1 {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteDOT writes graph in Graphviz DOT format. Unreachable tables are red, discovered relations are dashed.
+func (g *Graph) WriteDOT(out io.Writer) error {
+	lines := []string{"graph query {", "    rankdir=LR;", "    node [shape=record];"}
+	for _, table := range g.Tables {
+		title := escapeDOTRecord(table.Ref)
+		if table.Ref != table.Name {
+			title += " (" + escapeDOTRecord(table.Name) + ")"
+		}
+		if table.Driving && len(g.Interval) == 2 {
+			title += fmt.Sprintf("\\n%s: %d-%d", escapeDOTRecord(table.Columns[0]), g.Interval[0], g.Interval[1])
+		}
+		columns := make([]string, 0)
+		for _, column := range table.Columns {
+			columns = append(columns, escapeDOTRecord(column)+"\\l")
+		}
+		attributes := []string{fmt.Sprintf("label=\"{%s|%s}\"", title, strings.Join(columns, ""))}
+		switch {
+		case table.Driving:
+			attributes = append(attributes, "penwidth=2")
+		case !table.Selected:
+			attributes = append(attributes, "style=dashed", "color=gray", "fontcolor=gray")
+		case !table.Reachable:
+			attributes = append(attributes, "color=red", "fontcolor=red")
+		}
+		lines = append(lines, fmt.Sprintf("    %s [%s];", quoteDOT(table.Ref), strings.Join(attributes, ", ")))
+	}
+	for _, relation := range g.Relations {
+		attributes := []string{"label=" + quoteDOT(relation.Column1+" = "+relation.Column2)}
+		if relation.Discovered {
+			attributes = append(attributes, "style=dashed", "color=gray", "fontcolor=gray")
+		}
+		lines = append(lines, fmt.Sprintf("    %s -- %s [%s];", quoteDOT(relation.Table1), quoteDOT(relation.Table2), strings.Join(attributes, ", ")))
+	}
+	lines = append(lines, "}")
+	_, err := fmt.Fprintln(out, strings.Join(lines, "\n"))
+	return err
+}
+
+// WriteMermaid writes graph as Mermaid ER diagram. Unreachable tables are red, discovered relations are dotted.
+func (g *Graph) WriteMermaid(out io.Writer) error {
+	lines := []string{"erDiagram"}
+	for _, table := range g.Tables {
+		if table.Driving && len(g.Interval) == 2 {
+			lines = append(lines, fmt.Sprintf("    %%%% driving table %s: %s %d-%d", table.Ref, table.Columns[0], g.Interval[0], g.Interval[1]))
+		}
+		if len(table.Columns) == 0 {
+			lines = append(lines, "    "+quoteMermaid(table.Ref))
+			continue
+		}
+		lines = append(lines, "    "+quoteMermaid(table.Ref)+" {")
+		for _, column := range table.Columns {
+			lines = append(lines, "        column "+quoteMermaid(column))
+		}
+		lines = append(lines, "    }")
+	}
+	for _, relation := range g.Relations {
+		line := "--"
+		if relation.Discovered {
+			line = ".."
+		}
+		label := strings.Replace(relation.Column1+" = "+relation.Column2, "\"", "'", -1)
+		lines = append(lines, fmt.Sprintf("    %s }o%so{ %s : \"%s\"", quoteMermaid(relation.Table1), line, quoteMermaid(relation.Table2), label))
+	}
+	for _, table := range g.Tables {
+		if !table.Selected {
+			lines = append(lines, fmt.Sprintf("    style %s stroke:gray,stroke-dasharray:5", quoteMermaid(table.Ref)))
+		} else if !table.Reachable {
+			lines = append(lines, fmt.Sprintf("    style %s stroke:red,stroke-width:2px", quoteMermaid(table.Ref)))
+		}
+	}
+	_, err := fmt.Fprintln(out, strings.Join(lines, "\n"))
+	return err
+}
+
+func quoteDOT(s string) string {
+	return "\"" + strings.Replace(strings.Replace(s, "\\", "\\\\", -1), "\"", "\\\"", -1) + "\""
+}
+
+var dotRecordReplacer = strings.NewReplacer(
+	"\\", "\\\\", "\"", "\\\"", "{", "\\{", "}", "\\}", "|", "\\|", "<", "\\<", ">", "\\>",
+)
+
+func escapeDOTRecord(s string) string {
+	return dotRecordReplacer.Replace(s)
+}
+
+var mermaidNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func quoteMermaid(s string) string {
+	if mermaidNamePattern.MatchString(s) {
+		return s
+	}
+	return "\"" + strings.Replace(s, "\"", "'", -1) + "\""
+}
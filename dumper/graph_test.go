@@ -0,0 +1,114 @@
+package dumper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var graphQuery = &Query{
+	tables: []*QueryTable{
+		{"routes", []string{"id", "name"}, "", ""},
+		{"stations_for_routes", []string{"station_id", "route_id"}, "", ""},
+		{"stations", []string{"id"}, "start", ""},
+		{"logs", []string{"id"}, "", ""},
+	},
+	relations: []*QueryRelation{
+		{"routes", "id", "stations_for_routes", "route_id"},
+		{"start", "id", "stations_for_routes", "station_id"},
+	},
+	primaryInterval: []int64{1, 10},
+}
+
+func TestGraphUnreachable(t *testing.T) {
+	graph := NewGraph(graphQuery, nil)
+	assert.Equal(t, []string{"logs"}, graph.Unreachable())
+
+	disconnectedQuery := &Query{
+		tables: []*QueryTable{
+			{"routes", []string{"id"}, "", ""},
+			{"a", []string{"id"}, "", ""},
+			{"b", []string{"a_id"}, "", ""},
+		},
+		relations: []*QueryRelation{
+			{"a", "id", "b", "a_id"},
+		},
+		primaryInterval: []int64{1, 10},
+	}
+	assert.Equal(t, []string{"a", "b"}, NewGraph(disconnectedQuery, nil).Unreachable())
+}
+
+func TestGraphForeignKeys(t *testing.T) {
+	foreignKeys := []*ForeignKey{
+		{"fk_route", "stations_for_routes", "route_id", "routes", "id"},
+		{"fk_log_route", "logs", "route_id", "routes", "id"},
+		{"fk_owner", "routes", "owner_id", "users", "id"},
+		{"fk_other", "other", "user_id", "users", "id"},
+	}
+	graph := NewGraph(graphQuery, foreignKeys)
+	assert.Equal(t, 5, len(graph.Tables))
+	assert.Equal(t, "users", graph.Tables[4].Ref)
+	assert.False(t, graph.Tables[4].Selected)
+	assert.Equal(t, 4, len(graph.Relations))
+	assert.Equal(t, &GraphRelation{"routes", "id", "logs", "route_id", true}, graph.Relations[2])
+	assert.Equal(t, &GraphRelation{"users", "id", "routes", "owner_id", true}, graph.Relations[3])
+	assert.Equal(t, []string{"logs"}, graph.Unreachable())
+}
+
+func TestGraphWriteDOT(t *testing.T) {
+	graph := NewGraph(graphQuery, []*ForeignKey{{"fk_log_route", "logs", "route_id", "routes", "id"}})
+	out := &bytes.Buffer{}
+	err := graph.WriteDOT(out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "graph query {\n" +
+		"    rankdir=LR;\n" +
+		"    node [shape=record];\n" +
+		"    \"routes\" [label=\"{routes\\nid: 1-10|id\\lname\\l}\", penwidth=2];\n" +
+		"    \"stations_for_routes\" [label=\"{stations_for_routes|station_id\\lroute_id\\l}\"];\n" +
+		"    \"start\" [label=\"{start (stations)|id\\l}\"];\n" +
+		"    \"logs\" [label=\"{logs|id\\l}\", color=red, fontcolor=red];\n" +
+		"    \"routes\" -- \"stations_for_routes\" [label=\"id = route_id\"];\n" +
+		"    \"start\" -- \"stations_for_routes\" [label=\"id = station_id\"];\n" +
+		"    \"routes\" -- \"logs\" [label=\"id = route_id\", style=dashed, color=gray, fontcolor=gray];\n" +
+		"}\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestGraphWriteMermaid(t *testing.T) {
+	graph := NewGraph(graphQuery, []*ForeignKey{{"fk_owner", "routes", "owner_id", "app users", "id"}})
+	out := &bytes.Buffer{}
+	err := graph.WriteMermaid(out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "erDiagram\n" +
+		"    %% driving table routes: id 1-10\n" +
+		"    routes {\n" +
+		"        column id\n" +
+		"        column name\n" +
+		"    }\n" +
+		"    stations_for_routes {\n" +
+		"        column station_id\n" +
+		"        column route_id\n" +
+		"    }\n" +
+		"    start {\n" +
+		"        column id\n" +
+		"    }\n" +
+		"    logs {\n" +
+		"        column id\n" +
+		"    }\n" +
+		"    \"app users\"\n" +
+		"    routes }o--o{ stations_for_routes : \"id = route_id\"\n" +
+		"    start }o--o{ stations_for_routes : \"id = station_id\"\n" +
+		"    \"app users\" }o..o{ routes : \"id = owner_id\"\n" +
+		"    style logs stroke:red,stroke-width:2px\n" +
+		"    style \"app users\" stroke:gray,stroke-dasharray:5\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestEscapeDOTRecord(t *testing.T) {
+	assert.Equal(t, "a\\{b\\}\\|\\<c\\>\\\"", escapeDOTRecord("a{b}|<c>\""))
+	assert.Equal(t, "\"a\\\"b\"", quoteDOT("a\"b"))
+}
@@ -0,0 +1,238 @@
+package dumper
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// KeySet is interface which remembers keys and tells if key was added before
+type KeySet interface {
+	Add(key string) (added bool, err error)
+	Close() error
+}
+
+// MemoryKeySet keeps all keys in memory
+type MemoryKeySet struct {
+	keys map[string]bool
+}
+
+// NewMemoryKeySet builds new MemoryKeySet
+func NewMemoryKeySet() *MemoryKeySet {
+	return &MemoryKeySet{
+		make(map[string]bool),
+	}
+}
+
+// Add remembers key and returns false if it was added before
+func (ks *MemoryKeySet) Add(key string) (added bool, err error) {
+	if ks.keys[key] {
+		return false, nil
+	}
+	ks.keys[key] = true
+	return true, nil
+}
+
+// Close is part of interface. There is nothing to release for memory.
+func (ks *MemoryKeySet) Close() error {
+	return nil
+}
+
+// spillIndexInterval is number of keys in block of spilled file which is addressed by sparse index
+const spillIndexInterval = 64
+
+// spillBloomBitsPerKey is size of Bloom filter of spilled file, it gives about 1% of false positives
+const spillBloomBitsPerKey = 10
+
+// SpillKeySet keeps keys in memory until limit is reached and then moves them to sorted files on disk.
+// Every file has Bloom filter and sparse index in memory, so lookup of key reads at most one block of every file.
+type SpillKeySet struct {
+	limit   int
+	baseDir string
+	dir     string
+	keys    map[string]bool
+	runs    []*spillRun
+	logger  *slog.Logger
+}
+
+// spillRun is sorted file with keys which were moved to disk at once
+type spillRun struct {
+	file  *os.File
+	size  int64
+	bloom *bloomFilter
+	// index contains the first key of every block and offset of the block in file
+	index []spillIndexEntry
+}
+
+type spillIndexEntry struct {
+	key    string
+	offset int64
+}
+
+// NewSpillKeySet builds new SpillKeySet which stores files with keys in temporary directory inside baseDir
+func NewSpillKeySet(limit int, baseDir string) *SpillKeySet {
+	return &SpillKeySet{
+		limit:   limit,
+		baseDir: baseDir,
+		keys:    make(map[string]bool),
+		runs:    make([]*spillRun, 0),
+		logger:  discardLogger,
+	}
+}
+
+// Add remembers key and returns false if it was added before
+func (ks *SpillKeySet) Add(key string) (added bool, err error) {
+	if ks.keys[key] {
+		return false, nil
+	}
+	encodedKey := hex.EncodeToString([]byte(key))
+	for _, run := range ks.runs {
+		found, err := run.contains(encodedKey)
+		if err != nil || found {
+			return false, err
+		}
+	}
+	ks.keys[key] = true
+	if len(ks.keys) >= ks.limit {
+		err = ks.spill()
+	}
+	return true, err
+}
+
+// Close closes and removes files with keys
+func (ks *SpillKeySet) Close() error {
+	for _, run := range ks.runs {
+		run.file.Close()
+	}
+	ks.runs = nil
+	if ks.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(ks.dir)
+}
+
+// spill writes keys from memory into new sorted file
+func (ks *SpillKeySet) spill() (err error) {
+	if ks.dir == "" {
+		ks.dir, err = ioutil.TempDir(ks.baseDir, "sql-dumper-keys")
+		if err != nil {
+			return fmt.Errorf("Error at creating directory for keys: %s", err)
+		}
+	}
+	encodedKeys := make([]string, 0, len(ks.keys))
+	for key := range ks.keys {
+		encodedKeys = append(encodedKeys, hex.EncodeToString([]byte(key)))
+	}
+	sort.Strings(encodedKeys)
+
+	f, err := os.Create(filepath.Join(ks.dir, fmt.Sprintf("%06d.keys", len(ks.runs))))
+	if err != nil {
+		return fmt.Errorf("Error at writing keys to disk: %s", err)
+	}
+	run := &spillRun{
+		file:  f,
+		bloom: newBloomFilter(len(encodedKeys) * spillBloomBitsPerKey),
+		index: make([]spillIndexEntry, 0, len(encodedKeys)/spillIndexInterval+1),
+	}
+	w := bufio.NewWriter(f)
+	for i, encodedKey := range encodedKeys {
+		if i%spillIndexInterval == 0 {
+			run.index = append(run.index, spillIndexEntry{encodedKey, run.size})
+		}
+		run.bloom.add(encodedKey)
+		w.WriteString(encodedKey + "\n")
+		run.size += int64(len(encodedKey) + 1)
+	}
+	err = w.Flush()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("Error at writing keys to disk: %s", err)
+	}
+	ks.runs = append(ks.runs, run)
+	ks.keys = make(map[string]bool)
+	ks.logger.Debug("Keys are moved to disk", "file", f.Name(), "keys", len(encodedKeys))
+	return nil
+}
+
+// contains checks Bloom filter and reads block of file which can contain key
+func (run *spillRun) contains(encodedKey string) (found bool, err error) {
+	if !run.bloom.mayContain(encodedKey) {
+		return false, nil
+	}
+	block := sort.Search(len(run.index), func(i int) bool {
+		return run.index[i].key > encodedKey
+	}) - 1
+	if block < 0 {
+		return false, nil
+	}
+	end := run.size
+	if block+1 < len(run.index) {
+		end = run.index[block+1].offset
+	}
+	data := make([]byte, end-run.index[block].offset)
+	_, err = run.file.ReadAt(data, run.index[block].offset)
+	if err != nil {
+		return false, fmt.Errorf("Error at reading keys from disk: %s", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == encodedKey {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// bloomFilter tells that key was not added or that it was probably added
+type bloomFilter struct {
+	bits []uint64
+	size uint64
+}
+
+// bloomHashesCount is number of hash functions which is optimal for 10 bits per key
+const bloomHashesCount = 7
+
+func newBloomFilter(bitsCount int) *bloomFilter {
+	if bitsCount < 64 {
+		bitsCount = 64
+	}
+	return &bloomFilter{
+		bits: make([]uint64, (bitsCount+63)/64),
+		size: uint64((bitsCount + 63) / 64 * 64),
+	}
+}
+
+func (bf *bloomFilter) add(key string) {
+	h1, h2 := bloomHashes(key)
+	for i := uint64(0); i < bloomHashesCount; i++ {
+		bit := (h1 + i*h2) % bf.size
+		bf.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (bf *bloomFilter) mayContain(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for i := uint64(0); i < bloomHashesCount; i++ {
+		bit := (h1 + i*h2) % bf.size
+		if bf.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes returns two hashes which are combined into hash functions of Bloom filter
+func bloomHashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+	h = fnv.New64()
+	h.Write([]byte(key))
+	return h1, h.Sum64() | 1
+}
@@ -0,0 +1,104 @@
+package dumper
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestMemoryKeySet(t *testing.T) {
+	ks := NewMemoryKeySet()
+	defer ks.Close()
+	added, _ := ks.Add("a")
+	if !added {
+		t.Errorf("Expected new key to be added")
+	}
+	added, _ = ks.Add("a")
+	if added {
+		t.Errorf("Expected existing key not to be added")
+	}
+}
+
+func TestSpillKeySet(t *testing.T) {
+	ks := NewSpillKeySet(3, "")
+	for i := 0; i < 20; i++ {
+		added, err := ks.Add(fmt.Sprintf("key\n%d", i))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !added {
+			t.Errorf("Expected new key %d to be added", i)
+		}
+	}
+	if ks.dir == "" {
+		t.Fatalf("Expected keys to be moved to disk")
+	}
+	for i := 0; i < 20; i++ {
+		added, err := ks.Add(fmt.Sprintf("key\n%d", i))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if added {
+			t.Errorf("Expected existing key %d not to be added", i)
+		}
+	}
+	dir := ks.dir
+	ks.Close()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected directory with keys to be removed")
+	}
+}
+
+func TestSpillKeySetSeveralSpills(t *testing.T) {
+	ks := NewSpillKeySet(100, "")
+	defer ks.Close()
+	for i := 0; i < 10000; i += 2 {
+		added, err := ks.Add(fmt.Sprintf("%d", i))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !added {
+			t.Fatalf("Expected new key %d to be added", i)
+		}
+	}
+	if len(ks.runs) != 50 {
+		t.Fatalf("Expected 50 files with keys, but got %d", len(ks.runs))
+	}
+	for i := 0; i < 10000; i++ {
+		added, err := ks.Add(fmt.Sprintf("%d", i))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if added != (i%2 == 1) {
+			t.Fatalf("Expected added=%v for key %d", i%2 == 1, i)
+		}
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	bf := newBloomFilter(1000 * spillBloomBitsPerKey)
+	for i := 0; i < 1000; i++ {
+		bf.add(fmt.Sprintf("key%d", i))
+	}
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if !bf.mayContain(fmt.Sprintf("key%d", i)) {
+			t.Fatalf("Expected added key %d to be found", i)
+		}
+		if bf.mayContain(fmt.Sprintf("other%d", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("Expected about 1%% of false positives, but got %d of 1000", falsePositives)
+	}
+}
+
+func TestSpillKeySetDirError(t *testing.T) {
+	ks := NewSpillKeySet(1, "/not_existing_dir/keys")
+	defer ks.Close()
+	_, err := ks.Add("a")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
@@ -0,0 +1,26 @@
+package dumper
+
+import (
+	"context"
+	"io/ioutil"
+	"log/slog"
+)
+
+// discardLogger is used by parts of dumping until logger is set with WithLogger or WithFileLogger
+var discardLogger = slog.New(slog.NewTextHandler(ioutil.Discard, nil))
+
+// loggerKey is key of logger in context which is passed to data writers
+type loggerKey struct{}
+
+// contextWithLogger returns context which carries logger to data writers and queries
+func contextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// loggerFromContext returns logger which was set by Dump or logger which discards everything
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return discardLogger
+}
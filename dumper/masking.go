@@ -0,0 +1,243 @@
+package dumper
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskingRule represents definition how to change values of one column
+type MaskingRule struct {
+	table  string
+	column string
+	method string
+	param  string
+}
+
+var maskingMethods = []string{"fake", "hash", "null", "redact", "pseudo"}
+
+var fakeKinds = []string{"email", "name", "first_name", "last_name", "phone"}
+
+var fakeFirstNames = []string{
+	"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda",
+	"William", "Elizabeth", "David", "Barbara", "Richard", "Susan", "Joseph", "Jessica",
+}
+
+var fakeLastNames = []string{
+	"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis",
+	"Rodriguez", "Martinez", "Hernandez", "Lopez", "Wilson", "Anderson", "Thomas", "Taylor",
+}
+
+// ParseMaskingRules parses rules in format 'table1.column1=method;table2.column2=fake:kind'.
+// Names with separators of rules are quoted like in request, e.g. "my;table".email=null
+func ParseMaskingRules(rulesPart string) (rules []*MaskingRule, err error) {
+	rules = make([]*MaskingRule, 0)
+	if len(rulesPart) == 0 {
+		return rules, nil
+	}
+	p, err := newRequestParser(rulesPart)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		rule, err := p.parseMaskingRule()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+		if p.end() {
+			return rules, nil
+		}
+		if err = p.expectSeparator(';'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ReadMaskingRulesFile reads rules from file with one rule per line. Lines starting with # are skipped.
+func ReadMaskingRulesFile(filename string) (rules []*MaskingRule, err error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to read masking rules file: %v", err)
+	}
+	rules = make([]*MaskingRule, 0)
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseMaskingRule(line)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseMaskingRule(ruleDefinition string) (rule *MaskingRule, err error) {
+	p, err := newRequestParser(ruleDefinition)
+	if err != nil {
+		return nil, err
+	}
+	rule, err = p.parseMaskingRule()
+	if err != nil {
+		return nil, err
+	}
+	if !p.end() {
+		return nil, p.errorf("Expected end of masking rule, but got %s", p.peek())
+	}
+	return rule, nil
+}
+
+// parseMaskingRule reads rule in format 'table.column=method' or 'table.column=fake:kind'
+func (p *requestParser) parseMaskingRule() (*MaskingRule, error) {
+	table, column, err := p.parseRelationSide()
+	if err != nil {
+		return nil, err
+	}
+	if !p.skipSeparator('=') {
+		return nil, p.errorf("Masking rule should be in format 'table.column=method', but got %s", p.peek())
+	}
+	method, err := p.expectIdentifier("masking method")
+	if err != nil {
+		return nil, err
+	}
+	param := ""
+	if p.skipSeparator(':') {
+		param, err = p.expectIdentifier("parameter of masking method")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !contains(maskingMethods, method) {
+		return nil, fmt.Errorf("Unknown masking method '%s'. Available methods: %s", method, strings.Join(maskingMethods, ", "))
+	}
+	if method == "fake" && !contains(fakeKinds, param) {
+		return nil, fmt.Errorf("Unknown kind of fake values '%s'. Available kinds: %s", param, strings.Join(fakeKinds, ", "))
+	}
+	return &MaskingRule{table, column, method, param}, nil
+}
+
+// definition returns rule in format of ParseMaskingRules
+func (rule *MaskingRule) definition() string {
+	definition := rule.table + "." + formatIdentifier(rule.column) + "=" + rule.method
+	if rule.param != "" {
+		definition += ":" + formatIdentifier(rule.param)
+	}
+	return definition
+}
+
+// Masker changes values of rows using masking rules
+type Masker struct {
+	rules         []*MaskingRule
+	pseudonymizer *Pseudonymizer
+	// columnTypes contains types of columns mapped by table of rule and column, they limit pseudonyms of integers
+	columnTypes map[string]map[string]string
+}
+
+// NewMasker builds new Masker. Secret is used by pseudo method.
+func NewMasker(rules []*MaskingRule, secret string) *Masker {
+	return &Masker{
+		rules,
+		NewPseudonymizer(secret),
+		make(map[string]map[string]string),
+	}
+}
+
+// findRule returns rule for column of table which can be referenced by any of names
+func (m *Masker) findRule(tableNames []string, column string) *MaskingRule {
+	for _, rule := range m.rules {
+		if rule.column == column && contains(tableNames, rule.table) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// MaskRows changes values of rows. Rules are mapped by fields of rows.
+func (m *Masker) MaskRows(rules map[string]*MaskingRule, rows []*map[string]interface{}) {
+	if len(rules) == 0 {
+		return
+	}
+	for _, row := range rows {
+		for field, rule := range rules {
+			if v, ok := (*row)[field]; ok {
+				(*row)[field] = m.mask(rule, v)
+			}
+		}
+	}
+}
+
+func (m *Masker) mask(rule *MaskingRule, v interface{}) interface{} {
+	if v == nil || rule.method == "null" {
+		return nil
+	}
+	switch rule.method {
+	case "pseudo":
+		return m.pseudonymizer.PseudonymizeColumn(v, m.columnTypes[rule.table][rule.column])
+	case "hash":
+		return hashValue(v)
+	case "redact":
+		return redactValue(v)
+	case "fake":
+		return fakeValue(rule.param, v)
+	}
+	return v
+}
+
+// HasPseudoRules returns true if any rule uses pseudo method which requires secret
+func HasPseudoRules(rules []*MaskingRule) bool {
+	for _, rule := range rules {
+		if rule.method == "pseudo" {
+			return true
+		}
+	}
+	return false
+}
+
+func maskedValueToString(v interface{}) string {
+	if bytes, ok := v.([]uint8); ok {
+		return string(bytes)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func hashValue(v interface{}) string {
+	sum := sha256.Sum256([]byte(maskedValueToString(v)))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func redactValue(v interface{}) interface{} {
+	switch typedValue := v.(type) {
+	case int, int64, float64:
+		return 0
+	case string:
+		return strings.Repeat("*", utf8.RuneCountInString(typedValue))
+	case []uint8:
+		return strings.Repeat("*", utf8.RuneCount(typedValue))
+	}
+	return "*"
+}
+
+func fakeValue(kind string, v interface{}) string {
+	sum := sha256.Sum256([]byte(maskedValueToString(v)))
+	seed := binary.BigEndian.Uint64(sum[:8])
+	firstName := fakeFirstNames[seed%uint64(len(fakeFirstNames))]
+	lastName := fakeLastNames[(seed/uint64(len(fakeFirstNames)))%uint64(len(fakeLastNames))]
+	switch kind {
+	case "email":
+		return "user" + hex.EncodeToString(sum[:4]) + "@example.com"
+	case "first_name":
+		return firstName
+	case "last_name":
+		return lastName
+	case "phone":
+		return fmt.Sprintf("+1555%07d", seed%10000000)
+	}
+	return firstName + " " + lastName
+}
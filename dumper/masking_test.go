@@ -0,0 +1,205 @@
+package dumper
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+type testMaskingRulesInput struct {
+	rulesPart   string
+	expected    []*MaskingRule
+	expectedErr bool
+}
+
+var testsMaskingRules = []testMaskingRulesInput{
+	{
+		rulesPart: "users.email=fake:email;users.name=hash;users.phone=null;users.ssn=redact",
+		expected: []*MaskingRule{
+			{"users", "email", "fake", "email"},
+			{"users", "name", "hash", ""},
+			{"users", "phone", "null", ""},
+			{"users", "ssn", "redact", ""},
+		},
+	},
+	{
+		rulesPart: "core.users.email=hash;\"my.users\".`e-mail`=null",
+		expected: []*MaskingRule{
+			{"core.users", "email", "hash", ""},
+			{"\"my.users\"", "e-mail", "null", ""},
+		},
+	},
+	{
+		rulesPart: "\"a;b\".email=null;`c=d`.`e:f`=fake:email",
+		expected: []*MaskingRule{
+			{"\"a;b\"", "email", "null", ""},
+			{"\"c=d\"", "e:f", "fake", "email"},
+		},
+	},
+	{
+		rulesPart: "",
+		expected:  []*MaskingRule{},
+	},
+	{
+		rulesPart:   "users.email",
+		expectedErr: true,
+	},
+	{
+		rulesPart:   "users=hash",
+		expectedErr: true,
+	},
+	{
+		rulesPart:   "users.email=hash;",
+		expectedErr: true,
+	},
+	{
+		rulesPart:   "\"users.email=hash",
+		expectedErr: true,
+	},
+	{
+		rulesPart:   "a.b.c.d=hash",
+		expectedErr: true,
+	},
+	{
+		rulesPart:   "users.email=unknown",
+		expectedErr: true,
+	},
+	{
+		rulesPart:   "users.email=fake:unknown",
+		expectedErr: true,
+	},
+}
+
+func TestParseMaskingRules(t *testing.T) {
+	for _, input := range testsMaskingRules {
+		rules, err := ParseMaskingRules(input.rulesPart)
+		if err != nil && !input.expectedErr {
+			t.Errorf("Unexpected error: %s", err)
+			continue
+		}
+		if err == nil && input.expectedErr {
+			t.Errorf("Expected error for %s, but got nil", input.rulesPart)
+			continue
+		}
+		if !reflect.DeepEqual(rules, input.expected) {
+			t.Errorf("FOR %s EXP %v GOT %v", input.rulesPart, input.expected, rules)
+		}
+	}
+}
+
+func TestReadMaskingRulesFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "masking")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# contacts\nusers.email=fake:email\n\n  users.name=hash  \n")
+	f.Close()
+
+	rules, err := ReadMaskingRulesFile(f.Name())
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	expected := []*MaskingRule{
+		{"users", "email", "fake", "email"},
+		{"users", "name", "hash", ""},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("EXP %v GOT %v", expected, rules)
+	}
+
+	_, err = ReadMaskingRulesFile("not_existing_file")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestMaskRowsPseudoInRangeOfColumnType(t *testing.T) {
+	masker := NewMasker([]*MaskingRule{{"users", "level", "pseudo", ""}}, "secret")
+	masker.columnTypes = map[string]map[string]string{"users": {"level": "tinyint(4)"}}
+	rules := map[string]*MaskingRule{"level": masker.rules[0]}
+	rows := make([]*map[string]interface{}, 0)
+	for level := int64(100); level <= 127; level++ {
+		rows = append(rows, &map[string]interface{}{"level": level})
+	}
+	masker.MaskRows(rules, rows)
+	for _, row := range rows {
+		if level := (*row)["level"].(int64); level < 100 || level > 127 {
+			t.Errorf("Expected pseudonym in range of TINYINT, got %d", level)
+		}
+	}
+}
+
+func TestMaskRows(t *testing.T) {
+	masker := NewMasker([]*MaskingRule{
+		{"users", "email", "fake", "email"},
+		{"users", "name", "hash", ""},
+		{"users", "phone", "null", ""},
+		{"users", "ssn", "redact", ""},
+		{"users", "age", "redact", ""},
+	}, "")
+	rules := map[string]*MaskingRule{}
+	for _, column := range []string{"email", "name", "phone", "ssn", "age", "id"} {
+		if rule := masker.findRule([]string{"users"}, column); rule != nil {
+			rules[column] = rule
+		}
+	}
+	rows := []*map[string]interface{}{
+		{"id": 1, "email": "john@corp.com", "name": []uint8("John"), "phone": "123", "ssn": "123-45", "age": int64(30)},
+		{"id": 2, "email": nil, "name": "John", "phone": nil, "ssn": nil, "age": nil},
+	}
+	masker.MaskRows(rules, rows)
+
+	first := *rows[0]
+	if first["id"] != 1 {
+		t.Errorf("Expected not masked id, got %v", first["id"])
+	}
+	if !regexp.MustCompile(`^user[0-9a-f]{8}@example\.com$`).MatchString(first["email"].(string)) {
+		t.Errorf("Expected fake email, got %v", first["email"])
+	}
+	if first["name"] != (*rows[1])["name"] || first["name"] == "John" {
+		t.Errorf("Expected equal hashes for equal values, got %v and %v", first["name"], (*rows[1])["name"])
+	}
+	if first["phone"] != nil {
+		t.Errorf("Expected NULL, got %v", first["phone"])
+	}
+	if first["ssn"] != "******" {
+		t.Errorf("Expected redacted value, got %v", first["ssn"])
+	}
+	if first["age"] != 0 {
+		t.Errorf("Expected redacted number, got %v", first["age"])
+	}
+	if (*rows[1])["email"] != nil {
+		t.Errorf("Expected NULL to stay NULL, got %v", (*rows[1])["email"])
+	}
+}
+
+func TestFakeValue(t *testing.T) {
+	if fakeValue("name", "a") != fakeValue("name", []uint8("a")) {
+		t.Errorf("Expected deterministic fake values")
+	}
+	if !regexp.MustCompile(`^\+1555[0-9]{7}$`).MatchString(fakeValue("phone", "123")) {
+		t.Errorf("Unexpected fake phone: %s", fakeValue("phone", "123"))
+	}
+	if !contains(fakeFirstNames, fakeValue("first_name", "a")) {
+		t.Errorf("Unexpected fake first name: %s", fakeValue("first_name", "a"))
+	}
+	if !contains(fakeLastNames, fakeValue("last_name", "a")) {
+		t.Errorf("Unexpected fake last name: %s", fakeValue("last_name", "a"))
+	}
+}
+
+func TestMaskingRuleDefinition(t *testing.T) {
+	rules, err := ParseMaskingRules("\"a;b\".`e:f`=fake:email;core.users.id=pseudo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	definitions := []string{rules[0].definition(), rules[1].definition()}
+	expected := []string{"\"a;b\".\"e:f\"=fake:email", "core.users.id=pseudo"}
+	if !reflect.DeepEqual(definitions, expected) {
+		t.Errorf("EXP %v GOT %v", expected, definitions)
+	}
+}
@@ -0,0 +1,228 @@
+package dumper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const progressBarWidth = 30
+
+// ProgressReporter shows progress of dumping and collects summary of written tables.
+// It draws progress bar on terminal and prints periodic lines otherwise.
+type ProgressReporter struct {
+	out        io.Writer
+	tty        bool
+	interval   time.Duration
+	mutex      sync.Mutex
+	tables     []*TableSummary
+	current    string
+	rows       int64
+	bytes      int64
+	done       int
+	total      int
+	startedAt  time.Time
+	stopTicker chan bool
+	stopped    chan bool
+}
+
+// TableSummary contains result of dumping of one table
+type TableSummary struct {
+	Table           string  `json:"table"`
+	Rows            int64   `json:"rows"`
+	Bytes           int64   `json:"bytes"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	File            string  `json:"file"`
+}
+
+// DumpSummary contains result of the whole dump
+type DumpSummary struct {
+	Tables          []*TableSummary `json:"tables"`
+	Rows            int64           `json:"rows"`
+	Bytes           int64           `json:"bytes"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// NewProgressReporter builds new ProgressReporter which reports into out every interval
+func NewProgressReporter(out io.Writer, tty bool, interval time.Duration) *ProgressReporter {
+	return &ProgressReporter{
+		out:      out,
+		tty:      tty,
+		interval: interval,
+		tables:   make([]*TableSummary, 0),
+	}
+}
+
+// start starts periodic reports. Total is number of writes of tables which are expected.
+func (p *ProgressReporter) start(total int) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	p.total = total
+	p.startedAt = time.Now()
+	p.stopTicker = make(chan bool)
+	p.stopped = make(chan bool)
+	p.mutex.Unlock()
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.report()
+			case <-p.stopTicker:
+				return
+			}
+		}
+	}()
+}
+
+// stop stops periodic reports and prints the last one
+func (p *ProgressReporter) stop() {
+	if p == nil || p.stopTicker == nil {
+		return
+	}
+	close(p.stopTicker)
+	<-p.stopped
+	p.stopTicker = nil
+	p.report()
+	if p.tty {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// tableStarted sets table which is dumped now
+func (p *ProgressReporter) tableStarted(tableName string) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.current = tableName
+}
+
+// rowsFetched adds number of rows fetched from DB
+func (p *ProgressReporter) rowsFetched(count int) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.rows += int64(count)
+}
+
+// tableWritten adds rows and bytes which were written for table into file
+func (p *ProgressReporter) tableWritten(tableName string, filename string, rows int, bytes int64, duration time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.done++
+	p.bytes += bytes
+	for _, summary := range p.tables {
+		if summary.Table == tableName {
+			summary.Rows += int64(rows)
+			summary.Bytes += bytes
+			summary.DurationSeconds += duration.Seconds()
+			return
+		}
+	}
+	p.tables = append(p.tables, &TableSummary{tableName, int64(rows), bytes, duration.Seconds(), filename})
+}
+
+func (p *ProgressReporter) report() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	elapsed := time.Since(p.startedAt)
+	rate := int64(0)
+	if elapsed >= time.Second {
+		rate = p.rows * int64(time.Second) / int64(elapsed)
+	}
+	status := fmt.Sprintf("table %s, %d rows, %d rows/s, %s written, %s",
+		p.current, p.rows, rate, formatBytes(p.bytes), elapsed.Truncate(time.Second))
+	if p.tty {
+		fmt.Fprintf(p.out, "\r\033[K%s %3d%% %s", p.progressBar(), p.percent(), status)
+		return
+	}
+	fmt.Fprintf(p.out, "Progress: %d%% %s\n", p.percent(), status)
+}
+
+func (p *ProgressReporter) percent() int {
+	if p.total == 0 {
+		return 0
+	}
+	return p.done * 100 / p.total
+}
+
+func (p *ProgressReporter) progressBar() string {
+	filled := p.percent() * progressBarWidth / 100
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled) + "]"
+}
+
+// Summary returns summary of dumped tables. Error of dump is added if it is not nil.
+func (p *ProgressReporter) Summary(dumpErr error) *DumpSummary {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	summary := &DumpSummary{
+		Tables: p.tables,
+		Bytes:  p.bytes,
+	}
+	// Dump can fail before start, e.g. at DESCRIBE, then duration is zero
+	if !p.startedAt.IsZero() {
+		summary.DurationSeconds = time.Since(p.startedAt).Seconds()
+	}
+	for _, table := range p.tables {
+		summary.Rows += table.Rows
+	}
+	if dumpErr != nil {
+		summary.Error = dumpErr.Error()
+	}
+	return summary
+}
+
+// PrintSummary prints summary as table
+func PrintSummary(out io.Writer, summary *DumpSummary) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tROWS\tBYTES\tDURATION\tFILE")
+	for _, table := range summary.Tables {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%.2fs\t%s\n", table.Table, table.Rows, formatBytes(table.Bytes), table.DurationSeconds, table.File)
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t%s\t%.2fs\t\n", summary.Rows, formatBytes(summary.Bytes), summary.DurationSeconds)
+	tw.Flush()
+}
+
+// WriteSummaryJSON writes summary into file in JSON format
+func WriteSummaryJSON(filename string, summary *DumpSummary) error {
+	contents, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filename, contents, 0644)
+	if err != nil {
+		return fmt.Errorf("Fail to write summary file: %v", err)
+	}
+	return nil
+}
+
+func formatBytes(bytes int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(bytes)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
@@ -0,0 +1,161 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressReporter(t *testing.T) {
+	out := &bytes.Buffer{}
+	progress := NewProgressReporter(out, false, time.Hour)
+	progress.start(4)
+	progress.tableStarted("routes")
+	progress.rowsFetched(10)
+	progress.tableWritten("routes", "result.sql", 10, 2048, time.Second)
+	progress.tableStarted("stations")
+	progress.rowsFetched(5)
+	progress.tableWritten("stations", "result.sql", 5, 100, time.Second)
+	progress.tableWritten("routes", "result.sql", 3, 50, time.Second)
+	progress.stop()
+
+	assert.Contains(t, out.String(), "Progress: 75% table stations, 15 rows")
+	assert.Contains(t, out.String(), "2.1 KB written")
+
+	summary := progress.Summary(nil)
+	assert.Equal(t, int64(18), summary.Rows)
+	assert.Equal(t, int64(2198), summary.Bytes)
+	assert.Equal(t, 2, len(summary.Tables))
+	assert.Equal(t, &TableSummary{"routes", 13, 2098, 2, "result.sql"}, summary.Tables[0])
+	assert.Equal(t, "", summary.Error)
+
+	summary = progress.Summary(fmt.Errorf("Some DB error"))
+	assert.Equal(t, "Some DB error", summary.Error)
+}
+
+func TestProgressReporterSummaryWithoutStart(t *testing.T) {
+	progress := NewProgressReporter(&bytes.Buffer{}, false, time.Hour)
+	summary := progress.Summary(fmt.Errorf("File 'result.sql' already exists"))
+	assert.Equal(t, float64(0), summary.DurationSeconds)
+	assert.Equal(t, "File 'result.sql' already exists", summary.Error)
+}
+
+func TestProgressReporterTty(t *testing.T) {
+	out := &bytes.Buffer{}
+	progress := NewProgressReporter(out, true, time.Hour)
+	progress.start(2)
+	progress.tableStarted("routes")
+	progress.tableWritten("routes", "result.sql", 1, 10, time.Second)
+	progress.stop()
+
+	assert.Contains(t, out.String(), "\r\033[K[===============               ]  50% table routes")
+	assert.True(t, strings.HasSuffix(out.String(), "\n"))
+}
+
+func TestProgressReporterNil(t *testing.T) {
+	var progress *ProgressReporter
+	progress.start(1)
+	progress.tableStarted("routes")
+	progress.rowsFetched(1)
+	progress.tableWritten("routes", "result.sql", 1, 10, time.Second)
+	progress.stop()
+}
+
+func TestPrintSummary(t *testing.T) {
+	out := &bytes.Buffer{}
+	summary := &DumpSummary{
+		Tables: []*TableSummary{
+			{"routes", 13, 2098, 1.5, "out/routes.sql"},
+			{"stations", 5, 100, 0.25, "out/stations.sql"},
+		},
+		Rows:            18,
+		Bytes:           2198,
+		DurationSeconds: 2,
+	}
+	PrintSummary(out, summary)
+	expected := "TABLE     ROWS  BYTES   DURATION  FILE\n" +
+		"routes    13    2.0 KB  1.50s     out/routes.sql\n" +
+		"stations  5     100 B   0.25s     out/stations.sql\n" +
+		"TOTAL     18    2.1 KB  2.00s     \n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestWriteSummaryJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "summary")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "summary.json")
+
+	err = WriteSummaryJSON(filename, &DumpSummary{Tables: []*TableSummary{{"routes", 1, 10, 0.5, "result.sql"}}, Rows: 1, Bytes: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents, _ := ioutil.ReadFile(filename)
+	summary := &DumpSummary{}
+	json.Unmarshal(contents, summary)
+	assert.Equal(t, "routes", summary.Tables[0].Table)
+	assert.Equal(t, int64(10), summary.Bytes)
+
+	err = WriteSummaryJSON("/not_writtable/summary.json", summary)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "0 B", formatBytes(0))
+	assert.Equal(t, "1023 B", formatBytes(1023))
+	assert.Equal(t, "1.5 KB", formatBytes(1536))
+	assert.Equal(t, "3.0 GB", formatBytes(3*1024*1024*1024))
+}
+
+func TestQueryResultWithProgress(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"some_table", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 4},
+	}
+
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(3, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	out := &bytes.Buffer{}
+	options := &dumpOptions{chunkSize: 2, progress: NewProgressReporter(out, false, time.Hour)}
+	err = simpleQuery.dump(context.Background(), sqlxDB, NewSqlWriter(NewMemoryFileWriter(), "result.sql", ""), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Contains(t, out.String(), "Progress: 100% table some_table, 3 rows")
+	summary := options.progress.Summary(nil)
+	assert.Equal(t, int64(3), summary.Tables[0].Rows)
+	assert.Equal(t, "result.sql", summary.Tables[0].File)
+}
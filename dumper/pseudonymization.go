@@ -0,0 +1,182 @@
+package dumper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+const feistelRounds = 8
+
+// Pseudonymizer replaces values with deterministic pseudonyms using HMAC with secret.
+// Integers stay integers with the same number of digits in range of type of column, emails stay valid emails
+// and other strings keep positions of letters, digits and other characters.
+type Pseudonymizer struct {
+	secret []byte
+}
+
+// NewPseudonymizer builds new Pseudonymizer
+func NewPseudonymizer(secret string) *Pseudonymizer {
+	return &Pseudonymizer{
+		[]byte(secret),
+	}
+}
+
+// Pseudonymize returns pseudonym for value
+func (p *Pseudonymizer) Pseudonymize(v interface{}) interface{} {
+	return p.PseudonymizeColumn(v, "")
+}
+
+// PseudonymizeColumn returns pseudonym for value of column with type from DESCRIBE, e.g. int(11) unsigned.
+// Integers stay in range of integer type of column, so pseudonyms can be inserted into the same column.
+func (p *Pseudonymizer) PseudonymizeColumn(v interface{}, columnType string) interface{} {
+	bounds := getIntTypeBounds(columnType)
+	switch typedValue := v.(type) {
+	case nil:
+		return nil
+	case int:
+		return int(p.pseudonymizeInt(int64(typedValue), bounds))
+	case int64:
+		return p.pseudonymizeInt(typedValue, bounds)
+	case float64:
+		return typedValue
+	}
+	str := maskedValueToString(v)
+	if intValue, err := strconv.ParseInt(str, 10, 64); err == nil && strconv.FormatInt(intValue, 10) == str {
+		return p.pseudonymizeInt(intValue, bounds)
+	}
+	if atPos := strings.LastIndex(str, "@"); atPos > 0 && atPos < len(str)-1 {
+		return p.pseudonymizeEmail(str[:atPos], str[atPos+1:])
+	}
+	return p.pseudonymizeString("str", str)
+}
+
+// intTypeBounds contains the greatest absolute values of positive and negative numbers of integer type
+type intTypeBounds struct {
+	positive uint64
+	negative uint64
+}
+
+// getIntTypeBounds returns bounds of integer type of column. Other types are bounded by int64.
+func getIntTypeBounds(columnType string) intTypeBounds {
+	fields := strings.Fields(strings.ToLower(columnType))
+	if len(fields) == 0 {
+		return intTypeBounds{math.MaxInt64, math.MaxInt64 + 1}
+	}
+	bitsCount := map[string]uint{"tinyint": 8, "smallint": 16, "mediumint": 24, "int": 32, "integer": 32}
+	name := fields[0]
+	if bracket := strings.Index(name, "("); bracket >= 0 {
+		name = name[:bracket]
+	}
+	size, ok := bitsCount[name]
+	if !ok {
+		return intTypeBounds{math.MaxInt64, math.MaxInt64 + 1}
+	}
+	for _, field := range fields[1:] {
+		if field == "unsigned" {
+			return intTypeBounds{1<<size - 1, 0}
+		}
+	}
+	return intTypeBounds{1<<(size-1) - 1, 1 << (size - 1)}
+}
+
+// pseudonymizeInt permutes absolute value among numbers with the same count of digits within bounds of type
+func (p *Pseudonymizer) pseudonymizeInt(value int64, bounds intTypeBounds) int64 {
+	if value == math.MinInt64 {
+		return value
+	}
+	original := value
+	negative := value < 0
+	limit := bounds.positive
+	if negative {
+		value = -value
+		limit = bounds.negative
+	}
+	if uint64(value) > limit {
+		// Value does not fit into type, so it is not read from column of this type
+		return original
+	}
+	digits := len(strconv.FormatInt(value, 10))
+	var low, high uint64
+	if digits >= 19 {
+		low, high = uint64(math.Pow10(18)), uint64(math.MaxInt64)
+	} else if digits == 1 {
+		low, high = 0, 9
+	} else {
+		low, high = uint64(math.Pow10(digits-1)), uint64(math.Pow10(digits))-1
+	}
+	if high > limit {
+		high = limit
+	}
+	result := int64(low + p.permute(uint64(value)-low, high-low+1, digits))
+	if negative {
+		return -result
+	}
+	return result
+}
+
+// permute is format-preserving permutation of [0, size) built with Feistel network and cycle walking
+func (p *Pseudonymizer) permute(x uint64, size uint64, tweak int) uint64 {
+	halfBits := uint((bits.Len64(size-1) + 1) / 2)
+	if halfBits == 0 {
+		halfBits = 1
+	}
+	mask := uint64(1)<<halfBits - 1
+	for {
+		left, right := x>>halfBits, x&mask
+		for round := 0; round < feistelRounds; round++ {
+			left, right = right, left^(p.roundFunction(tweak, round, right)&mask)
+		}
+		x = left<<halfBits | right
+		if x < size {
+			return x
+		}
+	}
+}
+
+func (p *Pseudonymizer) roundFunction(tweak int, round int, value uint64) uint64 {
+	mac := hmac.New(sha256.New, p.secret)
+	buf := make([]byte, 17)
+	buf[0] = byte(round)
+	binary.BigEndian.PutUint64(buf[1:], uint64(tweak))
+	binary.BigEndian.PutUint64(buf[9:], value)
+	mac.Write(buf)
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+func (p *Pseudonymizer) pseudonymizeEmail(local string, domain string) string {
+	labels := strings.Split(domain, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		labels[i] = p.pseudonymizeString("domain", labels[i])
+	}
+	return p.pseudonymizeString("local", local) + "@" + strings.Join(labels, ".")
+}
+
+// pseudonymizeString replaces letters and digits with other ones of the same kind
+func (p *Pseudonymizer) pseudonymizeString(context string, str string) string {
+	stream := make([]byte, 0)
+	for counter := 0; len(stream) < len(str); counter++ {
+		mac := hmac.New(sha256.New, p.secret)
+		mac.Write([]byte(context + "\x00" + strconv.Itoa(counter) + "\x00" + str))
+		stream = append(stream, mac.Sum(nil)...)
+	}
+	result := []rune(str)
+	for i, r := range result {
+		k := int(stream[i%len(stream)])
+		switch {
+		case r >= 'a' && r <= 'z':
+			result[i] = rune('a' + k%26)
+		case r >= 'A' && r <= 'Z':
+			result[i] = rune('A' + k%26)
+		case r >= '0' && r <= '9':
+			result[i] = rune('0' + k%10)
+		case r > 127:
+			result[i] = rune('a' + k%26)
+		}
+	}
+	return string(result)
+}
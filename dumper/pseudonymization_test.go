@@ -0,0 +1,145 @@
+package dumper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestPseudonymizeInt(t *testing.T) {
+	p := NewPseudonymizer("secret")
+	unbounded := getIntTypeBounds("")
+	seen := make(map[int64]bool)
+	for i := int64(100); i < 1000; i++ {
+		pseudonym := p.pseudonymizeInt(i, unbounded)
+		if pseudonym < 100 || pseudonym > 999 {
+			t.Errorf("Expected 3 digits for %d, got %d", i, pseudonym)
+		}
+		if seen[pseudonym] {
+			t.Errorf("Expected unique pseudonyms, got duplicate %d", pseudonym)
+		}
+		seen[pseudonym] = true
+	}
+	if p.pseudonymizeInt(-123, unbounded) > -100 || p.pseudonymizeInt(-123, unbounded) < -999 {
+		t.Errorf("Expected negative number with 3 digits, got %d", p.pseudonymizeInt(-123, unbounded))
+	}
+	big := int64(9000000000000000000)
+	if p.pseudonymizeInt(big, unbounded) < 1000000000000000000 {
+		t.Errorf("Expected number with 19 digits, got %d", p.pseudonymizeInt(big, unbounded))
+	}
+	if p.pseudonymizeInt(7, unbounded) < 0 || p.pseudonymizeInt(7, unbounded) > 9 {
+		t.Errorf("Expected one digit, got %d", p.pseudonymizeInt(7, unbounded))
+	}
+}
+
+func TestPseudonymizeIntInRangeOfType(t *testing.T) {
+	p := NewPseudonymizer("secret")
+	intBounds := getIntTypeBounds("int(11)")
+	seen := make(map[int64]bool)
+	for i := int64(2147483547); i <= 2147483647; i++ {
+		pseudonym := p.pseudonymizeInt(i, intBounds)
+		if pseudonym < 1000000000 || pseudonym > 2147483647 {
+			t.Errorf("Expected 10 digits in range of INT for %d, got %d", i, pseudonym)
+		}
+		if seen[pseudonym] {
+			t.Errorf("Expected unique pseudonyms, got duplicate %d", pseudonym)
+		}
+		seen[pseudonym] = true
+	}
+	for i := int64(-2147483648); i <= -2147483548; i++ {
+		if pseudonym := p.pseudonymizeInt(i, intBounds); pseudonym > -1000000000 || pseudonym < -2147483648 {
+			t.Errorf("Expected negative 10 digits in range of INT for %d, got %d", i, pseudonym)
+		}
+	}
+
+	tinyintBounds := getIntTypeBounds("tinyint(4)")
+	seenTiny := make(map[int64]bool)
+	for i := int64(100); i <= 127; i++ {
+		pseudonym := p.pseudonymizeInt(i, tinyintBounds)
+		if pseudonym < 100 || pseudonym > 127 || seenTiny[pseudonym] {
+			t.Errorf("Expected unique 3 digits in range of TINYINT for %d, got %d", i, pseudonym)
+		}
+		seenTiny[pseudonym] = true
+	}
+	if pseudonym := p.pseudonymizeInt(-128, tinyintBounds); pseudonym > -100 || pseudonym < -128 {
+		t.Errorf("Expected negative 3 digits in range of TINYINT, got %d", pseudonym)
+	}
+	unsignedBounds := getIntTypeBounds("tinyint(3) unsigned")
+	for i := int64(200); i <= 255; i++ {
+		if pseudonym := p.pseudonymizeInt(i, unsignedBounds); pseudonym < 100 || pseudonym > 255 {
+			t.Errorf("Expected 3 digits in range of unsigned TINYINT for %d, got %d", i, pseudonym)
+		}
+	}
+	assert.Equal(t, int64(300), p.pseudonymizeInt(300, tinyintBounds))
+	assert.Equal(t, intTypeBounds{4294967295, 0}, getIntTypeBounds("int unsigned zerofill"))
+	assert.Equal(t, intTypeBounds{32767, 32768}, getIntTypeBounds("SMALLINT(6)"))
+	assert.Equal(t, getIntTypeBounds(""), getIntTypeBounds("varchar(255)"))
+	fromBytes := p.PseudonymizeColumn([]uint8("120"), "tinyint(4)").(int64)
+	assert.Equal(t, p.pseudonymizeInt(120, tinyintBounds), fromBytes)
+	assert.True(t, fromBytes >= 100 && fromBytes <= 127)
+}
+
+func TestPseudonymizeIsKeyed(t *testing.T) {
+	p1 := NewPseudonymizer("secret1")
+	p2 := NewPseudonymizer("secret2")
+	unbounded := getIntTypeBounds("")
+	differs := false
+	for i := int64(1000); i < 1010; i++ {
+		if p1.pseudonymizeInt(i, unbounded) != p2.pseudonymizeInt(i, unbounded) {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Errorf("Expected different pseudonyms for different secrets")
+	}
+}
+
+func TestPseudonymize(t *testing.T) {
+	p := NewPseudonymizer("secret")
+	if p.Pseudonymize(nil) != nil {
+		t.Errorf("Expected NULL to stay NULL")
+	}
+	fromBytes := p.Pseudonymize([]uint8("12345"))
+	fromInt := p.Pseudonymize(int64(12345))
+	if fromBytes != fromInt {
+		t.Errorf("Expected equal pseudonyms for bytes and int, got %v and %v", fromBytes, fromInt)
+	}
+	if _, ok := fromBytes.(int64); !ok {
+		t.Errorf("Expected integer, got %T", fromBytes)
+	}
+	if p.Pseudonymize(int(12345)) != int(fromInt.(int64)) {
+		t.Errorf("Expected equal pseudonyms for int and int64")
+	}
+
+	email := p.Pseudonymize("John.Doe@corp.example.com").(string)
+	if !regexp.MustCompile(`^[A-Z][a-z]{3}\.[A-Z][a-z]{2}@[a-z]{4}\.[a-z]{7}\.com$`).MatchString(email) {
+		t.Errorf("Expected valid email with the same format, got %s", email)
+	}
+	if email == "John.Doe@corp.example.com" {
+		t.Errorf("Expected changed email")
+	}
+
+	code := p.Pseudonymize("AB-007").(string)
+	if !regexp.MustCompile(`^[A-Z]{2}-[0-9]{3}$`).MatchString(code) {
+		t.Errorf("Expected the same format, got %s", code)
+	}
+	if p.Pseudonymize("AB-007") != code {
+		t.Errorf("Expected deterministic pseudonyms")
+	}
+	if p.Pseudonymize(1.5) != 1.5 {
+		t.Errorf("Expected float as is")
+	}
+}
+
+func TestPermute(t *testing.T) {
+	p := NewPseudonymizer("secret")
+	seen := make(map[uint64]bool)
+	for i := uint64(0); i < 10; i++ {
+		x := p.permute(i, 10, 1)
+		if x >= 10 || seen[x] {
+			t.Errorf("Expected permutation of [0, 10), got %s", strconv.FormatUint(x, 10))
+		}
+		seen[x] = true
+	}
+}
@@ -0,0 +1,780 @@
+package dumper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// QueryTable represents definition of one table for sql query.
+// Alias allows to use the same table several times with different roles.
+// Schema is set only for tables which are qualified by name of database.
+type QueryTable struct {
+	name    string
+	columns []string
+	alias   string
+	schema  string
+}
+
+// QueryRelation represents definition of relations between tables for sql query.
+// Tables are referenced by alias if it is set.
+type QueryRelation struct {
+	table1  string
+	column1 string
+	table2  string
+	column2 string
+}
+
+// Query represents information for building final sql queries
+type Query struct {
+	tables          []*QueryTable
+	relations       []*QueryRelation
+	primaryInterval []int64
+}
+
+// dumpOptions contains settings of dumping which are not part of query. They are changed by Option.
+type dumpOptions struct {
+	combined        bool
+	dedupSpillLimit int
+	dedupSpillDir   string
+	maskingRules    []*MaskingRule
+	maskingSecret   string
+	jobs            int
+	queryTimeout    time.Duration
+	chunkSize       int64
+	checkpoint      *Checkpoint
+	fileWriter      FileWriter
+	progress        *ProgressReporter
+	logger          *slog.Logger
+}
+
+// TableColumnDDL represents result row from DESCRIBE command
+type TableColumnDDL struct {
+	Field   string         `db:"Field"`
+	Type    string         `db:"Type"`
+	Null    string         `db:"Null"`
+	Key     string         `db:"Key"`
+	Default sql.NullString `db:"Default"`
+	Extra   string         `db:"Extra"`
+}
+
+// Result contains DDL and rows which were got from DB
+type Result struct {
+	DDL  *map[string]string
+	Rows *map[string]([]*map[string]interface{})
+}
+
+// dump selects rows of data from DB and writes them with writer
+func (q *Query) dump(ctx context.Context, db *sqlx.DB, writer DataWriter, options *dumpOptions) (err error) {
+	if len(q.primaryInterval) != 2 {
+		return fmt.Errorf("primaryInterval should contain two values")
+	}
+	combined := options.combined
+	if options.logger == nil {
+		options.logger = discardLogger
+	}
+	ctx = contextWithLogger(ctx, options.logger)
+
+	if options.checkpoint != nil {
+		err = options.checkpoint.prepare(q, writer, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	descriptions, err := q.describeTables(ctx, db)
+	if err != nil {
+		return
+	}
+	ddls, err := q.toDDLFromDescriptions(descriptions)
+	if err != nil {
+		return
+	}
+	if !options.checkpoint.isDDLWritten() {
+		ddlFiles := make([]string, 0)
+		writtenDDLs := make(map[string]bool)
+		for _, qt := range q.tables {
+			tableName := qt.fullName()
+			tableDDL, ok := ddls[tableName]
+			if !ok || writtenDDLs[tableName] {
+				continue
+			}
+			err = writer.WriteDDL(ctx, tableName, tableDDL)
+			if err != nil {
+				return
+			}
+			writtenDDLs[tableName] = true
+			options.logger.Debug("DDL is written", "table", tableName)
+			if filename := getTargetName(writer, tableName); !contains(ddlFiles, filename) {
+				ddlFiles = append(ddlFiles, filename)
+			}
+		}
+		err = options.checkpoint.commitDDL(ddlFiles)
+		if err != nil {
+			return
+		}
+	}
+
+	deduplicator := q.newRowsDeduplicator(descriptions, options)
+	defer deduplicator.Close()
+	masker := NewMasker(q.expandMaskingRules(options.maskingRules), options.maskingSecret)
+	masker.columnTypes = q.getColumnTypes(descriptions)
+	chunks := splitInterval(q.primaryInterval[0], q.primaryInterval[1], options.chunkSize)
+	options.logger.Info("Dumping is started", "tables", len(q.tables), "from", q.primaryInterval[0], "to", q.primaryInterval[1], "chunks", len(chunks), "combined", combined, "jobs", options.jobs)
+	if combined {
+		options.progress.start(len(chunks))
+	} else {
+		options.progress.start(len(chunks) * len(q.tables))
+	}
+	defer options.progress.stop()
+	for _, chunk := range chunks {
+		options.logger.Debug("Dumping chunk", "from", chunk[0], "to", chunk[1])
+		err = q.withInterval(chunk).selectAndWrite(ctx, db, writer, combined, deduplicator, masker, options)
+		if err != nil {
+			return
+		}
+	}
+	options.logger.Info("Dumping is finished")
+
+	if options.checkpoint != nil {
+		return options.checkpoint.remove()
+	}
+	return
+}
+
+// withInterval returns copy of query with another interval of values for the first column
+func (q *Query) withInterval(interval []int64) *Query {
+	chunkQuery := *q
+	chunkQuery.primaryInterval = interval
+	return &chunkQuery
+}
+
+func (q *Query) newRowsDeduplicator(descriptions map[string][]TableColumnDDL, options *dumpOptions) *RowsDeduplicator {
+	primaryKeys := make(map[string][]string)
+	aliased := make(map[string]bool)
+	for tableName, tableDescribtion := range descriptions {
+		primaryKeys[tableName] = getPrimaryKeysFromTableDescription(tableDescribtion)
+		aliased[tableName] = q.isAliased(tableName)
+	}
+	deduplicator := NewRowsDeduplicator(primaryKeys, aliased, options.dedupSpillLimit, options.dedupSpillDir)
+	deduplicator.logger = options.logger
+	return deduplicator
+}
+
+func (q *Query) selectAndWrite(ctx context.Context, db *sqlx.DB, writer DataWriter, combined bool, deduplicator *RowsDeduplicator, masker *Masker, options *dumpOptions) (err error) {
+	if combined {
+		if options.checkpoint.isDone("combined", q.primaryInterval[1]) {
+			return nil
+		}
+		options.progress.tableStarted("combined")
+		query := q.toSqlForCombinedRows()
+		startedAt := time.Now()
+		resultsMaps, err := dbSelectWithTimeout(ctx, options.queryTimeout, db, query, q.primaryInterval[0], q.primaryInterval[1])
+		if err != nil {
+			return err
+		}
+		options.progress.rowsFetched(len(resultsMaps))
+		if masker != nil {
+			masker.MaskRows(q.getMaskingRulesForCombinedRows(masker), resultsMaps)
+		}
+		return q.writeRows(ctx, writer, options, "combined", "combined", q.getAllColumns(), resultsMaps, time.Since(startedAt))
+	} else {
+		queries, err := q.toSqlForTables()
+		if err != nil {
+			return err
+		}
+		pendingTables := make([]*QueryTable, 0)
+		pendingQueries := make([]string, 0)
+		for i, qt := range q.tables {
+			if options.checkpoint.isDone(qt.ref(), q.primaryInterval[1]) {
+				loggerFromContext(ctx).Debug("Table is skipped as written before", "table", qt.ref(), "to", q.primaryInterval[1])
+				err = q.restoreWrittenKeys(ctx, db, deduplicator, qt, queries[i], options)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			pendingTables = append(pendingTables, qt)
+			pendingQueries = append(pendingQueries, queries[i])
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		results := selectConcurrently(ctx, db, pendingQueries, options.jobs, options.queryTimeout, q.primaryInterval[0], q.primaryInterval[1])
+		for i, qt := range pendingTables {
+			options.progress.tableStarted(qt.ref())
+			var result *selectResult
+			select {
+			case result = <-results[i]:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if result.err != nil {
+				return result.err
+			}
+			resultsMaps := result.rows
+			options.progress.rowsFetched(len(resultsMaps))
+			columns := q.tableColumns(qt.fullName())
+			if deduplicator != nil {
+				resultsMaps, err = deduplicator.Filter(qt.fullName(), columns, resultsMaps)
+				if err != nil {
+					return err
+				}
+			}
+			if masker != nil {
+				masker.MaskRows(q.getMaskingRulesForTable(masker, qt), resultsMaps)
+			}
+			err = q.writeRows(ctx, writer, options, qt.ref(), qt.fullName(), columns, resultsMaps, result.duration)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreWrittenKeys selects again rows of table which were written before resuming and remembers their keys,
+// so rows which are selected again in next chunks are skipped like in dump without failure
+func (q *Query) restoreWrittenKeys(ctx context.Context, db *sqlx.DB, deduplicator *RowsDeduplicator, qt *QueryTable, query string, options *dumpOptions) error {
+	columns := q.tableColumns(qt.fullName())
+	if deduplicator == nil || !deduplicator.remembers(qt.fullName(), columns) {
+		return nil
+	}
+	rows, err := dbSelectWithTimeout(ctx, options.queryTimeout, db, query, q.primaryInterval[0], q.primaryInterval[1])
+	if err != nil {
+		return err
+	}
+	_, err = deduplicator.Filter(qt.fullName(), columns, rows)
+	loggerFromContext(ctx).Debug("Keys of written rows are restored", "table", qt.ref(), "rows", len(rows))
+	return err
+}
+
+// writeRows writes rows of table and saves progress of the table in the current interval
+func (q *Query) writeRows(ctx context.Context, writer DataWriter, options *dumpOptions, tableRef string, tableName string, columns []string, rows []*map[string]interface{}, queryDuration time.Duration) (err error) {
+	filename := getTargetName(writer, tableName)
+	startedAt := time.Now()
+	sizeBefore := writtenFileSize(writer, filename)
+	err = writer.WriteRows(ctx, tableName, columns, rows)
+	if err != nil {
+		return err
+	}
+	sizeAfter := writtenFileSize(writer, filename)
+	options.progress.tableWritten(tableRef, filename, len(rows), sizeAfter-sizeBefore, queryDuration+time.Since(startedAt))
+	return options.checkpoint.commit(tableRef, q.primaryInterval[1], filename)
+}
+
+// toSqlForTables returns queries for every table in order of tables
+func (q *Query) toSqlForTables() (queries []string, err error) {
+	queries = make([]string, 0)
+	for i, qt := range q.tables {
+		var query string
+		if i == 0 {
+			query = q.toSqlForSingleTable(qt)
+		} else {
+			query, err = q.toSqlForRelation(qt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+type selectResult struct {
+	rows     []*map[string]interface{}
+	err      error
+	duration time.Duration
+}
+
+// selectConcurrently runs queries using not more than jobs connections at once.
+// Result of every query is sent to its own channel, so results can be read in order of queries.
+func selectConcurrently(ctx context.Context, db *sqlx.DB, queries []string, jobs int, queryTimeout time.Duration, args ...interface{}) (results []chan *selectResult) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	results = make([]chan *selectResult, len(queries))
+	for i := range queries {
+		results[i] = make(chan *selectResult, 1)
+	}
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range queries {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < jobs && w < len(queries); w++ {
+		go func() {
+			for i := range indexes {
+				startedAt := time.Now()
+				rows, err := dbSelectWithTimeout(ctx, queryTimeout, db, queries[i], args...)
+				results[i] <- &selectResult{rows, err, time.Since(startedAt)}
+			}
+		}()
+	}
+	return results
+}
+
+// dbSelectWithTimeout runs query which is cancelled after timeout. Zero timeout means no timeout.
+func dbSelectWithTimeout(ctx context.Context, timeout time.Duration, db *sqlx.DB, query string, args ...interface{}) (resultsMaps []*map[string]interface{}, err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	resultsMaps, err = dbSelect(ctx, db, query, args...)
+	if err != nil && timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("Query exceeded timeout %s: %s", timeout, err)
+	}
+	return resultsMaps, err
+}
+
+func dbSelect(ctx context.Context, db *sqlx.DB, query string, args ...interface{}) (resultsMaps []*map[string]interface{}, err error) {
+	startedAt := time.Now()
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		loggerFromContext(ctx).Debug("Query failed", "query", query, "args", args, "duration", time.Since(startedAt), "error", err)
+		return
+	}
+	defer rows.Close()
+
+	resultsMaps = make([]*map[string]interface{}, 0)
+	for rows.Next() {
+		results := make(map[string]interface{})
+		rows.MapScan(results)
+		resultsMaps = append(resultsMaps, &results)
+	}
+	if err = rows.Err(); err != nil {
+		loggerFromContext(ctx).Debug("Query failed", "query", query, "args", args, "duration", time.Since(startedAt), "error", err)
+		return nil, err
+	}
+	loggerFromContext(ctx).Info("Query finished", "query", query, "args", args, "rows", len(resultsMaps), "duration", time.Since(startedAt))
+	return resultsMaps, nil
+}
+
+func (q *Query) toSqlForSingleTable(qt *QueryTable) (str string) {
+	str = "SELECT " + qt.sqlPartForColumns(q.tableColumns(qt.fullName())) + "\n"
+	str += "FROM " + sqlTableWithAlias(qt) + "\n"
+	str += "WHERE " + sqlTableAndColumn(qt.ref(), qt.columns[0]) + " BETWEEN ? AND ?"
+	return str
+}
+
+func (q *Query) toSqlForRelation(qt *QueryTable) (str string, err error) {
+	subquery, leftTableColumn, err := q.toSqlSubQueryForRelation(qt)
+	if err != nil {
+		return
+	}
+	str = "SELECT " + qt.sqlPartForColumns(q.tableColumns(qt.fullName())) + "\n"
+	str += "FROM " + sqlTableWithAlias(qt) + "\n"
+	str += "WHERE " + leftTableColumn + " IN\n"
+	str += "(\n" + subquery + "\n)"
+	return
+}
+
+func (q *Query) toSqlForCombinedRows() (str string) {
+	selectTables := make([]string, 0)
+	selectColumns := make([]string, 0)
+	conditions := make([]string, 0)
+	for _, qt := range q.tables {
+		selectTables = append(selectTables, sqlTableWithAlias(qt))
+		for _, col := range qt.columns {
+			selectColumns = append(selectColumns, sqlTableAndColumn(qt.ref(), col)+" AS "+sqlColumn(qt.ref()+"."+col))
+		}
+	}
+	for _, r := range q.relations {
+		conditions = append(conditions, sqlTableAndColumn(r.table1, r.column1)+" = "+sqlTableAndColumn(r.table2, r.column2))
+	}
+	conditions = append(conditions, sqlTableAndColumn(q.tables[0].ref(), q.tables[0].columns[0])+" BETWEEN ? AND ?")
+	str = "SELECT " + strings.Join(selectColumns, ", ") + "\n"
+	str += "FROM " + strings.Join(selectTables, ", ") + "\n"
+	str += "WHERE (" + strings.Join(conditions, ") AND (") + ")"
+	return
+}
+
+func (q *Query) toDDL(ctx context.Context, db *sqlx.DB) (ddls map[string]string, err error) {
+	descriptions, err := q.describeTables(ctx, db)
+	if err != nil {
+		return make(map[string]string, 0), err
+	}
+	return q.toDDLFromDescriptions(descriptions)
+}
+
+func (q *Query) describeTables(ctx context.Context, db *sqlx.DB) (descriptions map[string][]TableColumnDDL, err error) {
+	descriptions = make(map[string][]TableColumnDDL)
+	for _, qt := range q.tables {
+		if _, ok := descriptions[qt.fullName()]; ok {
+			continue
+		}
+		tableDescribtion, err := getTableDescription(ctx, db, qt.fullName())
+		if err != nil {
+			return descriptions, err
+		}
+		descriptions[qt.fullName()] = tableDescribtion
+	}
+	return descriptions, nil
+}
+
+func (q *Query) toDDLFromDescriptions(descriptions map[string][]TableColumnDDL) (ddls map[string]string, err error) {
+	ddls = make(map[string]string, 0)
+	relations := q.physicalRelations()
+	for _, qt := range q.tables {
+		if _, ok := ddls[qt.fullName()]; ok {
+			continue
+		}
+		tableDDL, err := makeDDLFromTableDescription(qt.fullName(), descriptions[qt.fullName()], q.tableColumns(qt.fullName()), relations)
+		if err != nil {
+			return ddls, err
+		}
+		if qt.schema != "" {
+			// Tables of other databases are created with qualified names, so their databases should exist
+			tableDDL = "CREATE DATABASE IF NOT EXISTS " + sqlTable(qt.schema) + ";\n" + tableDDL
+		}
+		ddls[qt.fullName()] = tableDDL
+	}
+	return ddls, nil
+}
+
+func getTableDescription(ctx context.Context, db *sqlx.DB, tableName string) (tableDescribtion []TableColumnDDL, err error) {
+	columnsDDL := []TableColumnDDL{}
+	err = db.SelectContext(ctx, &columnsDDL, "DESCRIBE "+sqlName(tableName))
+	return columnsDDL, err
+}
+
+func makeDDLFromTableDescription(tableName string, tableDescribtion []TableColumnDDL, columnsOnly []string, relations []*QueryRelation) (tableDDL string, err error) {
+	columnsDDLs := []string{}
+	primaryKeys := []string{}
+	indexColumns := []string{}
+	uniqueColumns := []string{}
+	possibleFKDefs := map[string]string{}
+	for _, columnDescr := range tableDescribtion {
+		if !contains(columnsOnly, columnDescr.Field) {
+			continue
+		}
+		columnDDL := sqlColumn(columnDescr.Field) + " " + columnDescr.Type + " "
+		if columnDescr.Null == "YES" {
+			columnDDL += "NULL"
+		} else {
+			columnDDL += "NOT NULL"
+		}
+		if columnDescr.Default.Valid {
+			columnDDL += " "
+			columnDDL += "DEFAULT '" + columnDescr.Default.String + "'"
+		}
+		columnsDDLs = append(columnsDDLs, columnDDL)
+		if columnDescr.Key == "PRI" {
+			primaryKeys = append(primaryKeys, sqlColumn(columnDescr.Field))
+		}
+		if columnDescr.Key == "MUL" {
+			indexColumns = append(indexColumns, sqlColumn(columnDescr.Field))
+		}
+		if columnDescr.Key == "UNI" {
+			uniqueColumns = append(uniqueColumns, sqlColumn(columnDescr.Field))
+		}
+		rTable, rColumn, _ := findRelation(relations, tableName, columnDescr.Field)
+		if rColumn != "" {
+			possibleFKDefs[sqlColumn(columnDescr.Field)] = "CONSTRAINT " + sqlColumn("fk_"+columnDescr.Field) + " FOREIGN KEY (" + sqlColumn(columnDescr.Field) + ") REFERENCES " + sqlName(rTable) + " (" + sqlColumn(rColumn) + ") ON DELETE CASCADE"
+		}
+	}
+
+	if len(columnsDDLs) == 0 {
+		return "", fmt.Errorf("Table '%s' contains 0 of specified fields", tableName)
+	}
+
+	rows := columnsDDLs
+	if len(primaryKeys) > 0 {
+		rows = append(rows, "PRIMARY KEY ("+strings.Join(primaryKeys, ", ")+")")
+	}
+	for _, ind := range indexColumns {
+		rows = append(rows, "INDEX "+ind+" ("+ind+")")
+	}
+	for _, unq := range uniqueColumns {
+		rows = append(rows, "UNIQUE INDEX "+unq+" ("+unq+")")
+	}
+	for column, fk := range possibleFKDefs {
+		if !(len(primaryKeys) == 1 && primaryKeys[0] == column) {
+			rows = append(rows, fk)
+		}
+	}
+
+	for i, row := range rows {
+		rows[i] = "    " + row
+	}
+
+	tableDDL = "CREATE TABLE " + sqlName(tableName) + " (\n"
+	tableDDL += strings.Join(rows, ",\n")
+	tableDDL += "\n"
+	tableDDL += ");"
+	return tableDDL, nil
+}
+
+// ref returns name which is used to reference the table in queries: alias or full name of table
+func (qt *QueryTable) ref() string {
+	if qt.alias != "" {
+		return formatIdentifier(qt.alias)
+	}
+	return qt.fullName()
+}
+
+// fullName returns name of physical table in format of request: table or schema.table
+func (qt *QueryTable) fullName() string {
+	return qualifiedName(qt.schema, qt.name)
+}
+
+func (qt *QueryTable) sqlPartForSelectColumns() string {
+	return qt.sqlPartForColumns(qt.columns)
+}
+
+func (qt *QueryTable) sqlPartForColumns(columns []string) string {
+	selectFields := make([]string, 0)
+	for _, qtcol := range columns {
+		selectFields = append(selectFields, sqlTableAndColumn(qt.ref(), qtcol))
+	}
+	return strings.Join(selectFields, ", ")
+}
+
+func sqlTable(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// sqlName quotes every part of name in format of request, e.g. schema.table becomes `schema`.`table`
+func sqlName(name string) string {
+	parts, err := splitQualifiedName(name)
+	if err != nil {
+		return sqlTable(name)
+	}
+	for i, part := range parts {
+		parts[i] = sqlTable(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func sqlTableWithAlias(qt *QueryTable) string {
+	if qt.alias != "" {
+		return sqlName(qt.fullName()) + " AS " + sqlTable(qt.alias)
+	}
+	return sqlName(qt.fullName())
+}
+
+func sqlColumn(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// sqlTableAndColumn returns quoted column of table, which is referenced in format of request
+func sqlTableAndColumn(table string, column string) string {
+	return sqlName(table) + "." + sqlColumn(column)
+}
+
+func (q *Query) toSqlSubQueryForRelation(mainTable *QueryTable) (subquery string, leftTableColumn string, err error) {
+	if mainTable.ref() == q.tables[0].ref() {
+		return "", "", fmt.Errorf("Cannot build subquery for first table in list")
+	}
+
+	rightTableColumn := ""
+	for _, qr := range q.relations {
+		if qr.table1 == mainTable.ref() {
+			leftTableColumn = sqlTableAndColumn(qr.table1, qr.column1)
+			rightTableColumn = sqlTableAndColumn(qr.table2, qr.column2)
+			break
+		}
+		if qr.table2 == mainTable.ref() {
+			leftTableColumn = sqlTableAndColumn(qr.table2, qr.column2)
+			rightTableColumn = sqlTableAndColumn(qr.table1, qr.column1)
+			break
+		}
+	}
+	if rightTableColumn == "" {
+		return "", "", fmt.Errorf("Cannot find relation for table '%s'. Relations: %v", mainTable.ref(), q.relations)
+	}
+
+	subquery = "SELECT " + rightTableColumn + "\n"
+	subquery += "FROM "
+	selectTables := make([]string, 0)
+	for _, qt := range q.tables {
+		if mainTable == qt {
+			continue
+		}
+		selectTables = append(selectTables, sqlTableWithAlias(qt))
+	}
+	subquery += strings.Join(selectTables, ", ")
+	subquery += "\n"
+	subquery += "WHERE "
+	whereConditions := make([]string, 0)
+	firstTable := q.tables[0]
+	firstCondition := sqlTableAndColumn(firstTable.ref(), firstTable.columns[0]) + " BETWEEN ? AND ?"
+	whereConditions = append(whereConditions, firstCondition)
+	for _, qr := range q.relations {
+		if qr.table1 == mainTable.ref() || qr.table2 == mainTable.ref() {
+			continue
+		}
+		condition := sqlTableAndColumn(qr.table1, qr.column1) + " = " + sqlTableAndColumn(qr.table2, qr.column2)
+		whereConditions = append(whereConditions, condition)
+	}
+	subquery += "(" + strings.Join(whereConditions, ") AND (") + ")"
+	return
+}
+
+func (q *Query) getAllColumns() (columns []string) {
+	columns = make([]string, 0)
+	for _, qt := range q.tables {
+		for _, col := range qt.columns {
+			columns = append(columns, qt.ref()+"."+col)
+		}
+	}
+	return
+}
+
+// expandMaskingRules adds pseudo rules to columns which are related to pseudonymized columns,
+// so both sides of every relation get the same values
+func (q *Query) expandMaskingRules(rules []*MaskingRule) (expanded []*MaskingRule) {
+	expanded = append([]*MaskingRule{}, rules...)
+	masker := NewMasker(expanded, "")
+	findPseudoRule := func(ref string, column string) *MaskingRule {
+		qt := q.findTable(ref)
+		if qt == nil {
+			return nil
+		}
+		rule := masker.findRule([]string{qt.fullName(), qt.ref()}, column)
+		if rule == nil || rule.method != "pseudo" {
+			return nil
+		}
+		return rule
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, qr := range q.relations {
+			rule1 := findPseudoRule(qr.table1, qr.column1)
+			rule2 := findPseudoRule(qr.table2, qr.column2)
+			if rule1 != nil && rule2 == nil && q.findTable(qr.table2) != nil {
+				masker.rules = append(masker.rules, &MaskingRule{qr.table2, qr.column2, rule1.method, rule1.param})
+				changed = true
+			}
+			if rule2 != nil && rule1 == nil && q.findTable(qr.table1) != nil {
+				masker.rules = append(masker.rules, &MaskingRule{qr.table1, qr.column1, rule2.method, rule2.param})
+				changed = true
+			}
+		}
+	}
+	return masker.rules
+}
+
+// findTable returns table by alias or name
+func (q *Query) findTable(ref string) *QueryTable {
+	for _, qt := range q.tables {
+		if qt.ref() == ref {
+			return qt
+		}
+	}
+	return nil
+}
+
+// getColumnTypes returns types of columns from descriptions mapped by name and by reference of every table
+func (q *Query) getColumnTypes(descriptions map[string][]TableColumnDDL) map[string]map[string]string {
+	columnTypes := make(map[string]map[string]string)
+	for _, qt := range q.tables {
+		types := make(map[string]string)
+		for _, column := range descriptions[qt.fullName()] {
+			types[column.Field] = column.Type
+		}
+		columnTypes[qt.fullName()] = types
+		columnTypes[qt.ref()] = types
+	}
+	return columnTypes
+}
+
+// getMaskingRulesForTable returns masking rules mapped by selected columns of table
+func (q *Query) getMaskingRulesForTable(masker *Masker, qt *QueryTable) (rules map[string]*MaskingRule) {
+	rules = make(map[string]*MaskingRule)
+	for _, col := range q.tableColumns(qt.fullName()) {
+		if rule := masker.findRule([]string{qt.fullName(), qt.ref()}, col); rule != nil {
+			rules[col] = rule
+		}
+	}
+	return
+}
+
+// getMaskingRulesForCombinedRows returns masking rules mapped by columns of combined rows
+func (q *Query) getMaskingRulesForCombinedRows(masker *Masker) (rules map[string]*MaskingRule) {
+	rules = make(map[string]*MaskingRule)
+	for _, qt := range q.tables {
+		for _, col := range qt.columns {
+			if rule := masker.findRule([]string{qt.fullName(), qt.ref()}, col); rule != nil {
+				rules[qt.ref()+"."+col] = rule
+			}
+		}
+	}
+	return
+}
+
+// tableColumns returns columns of physical table from all its aliases without duplicates
+func (q *Query) tableColumns(tableName string) (columns []string) {
+	columns = make([]string, 0)
+	for _, qt := range q.tables {
+		if qt.fullName() != tableName {
+			continue
+		}
+		for _, col := range qt.columns {
+			if !contains(columns, col) {
+				columns = append(columns, col)
+			}
+		}
+	}
+	return
+}
+
+// isAliased returns true if physical table is used in query more than once
+func (q *Query) isAliased(tableName string) bool {
+	count := 0
+	for _, qt := range q.tables {
+		if qt.fullName() == tableName {
+			count++
+		}
+	}
+	return count > 1
+}
+
+// physicalRelations returns relations where aliases are replaced with names of tables
+func (q *Query) physicalRelations() (relations []*QueryRelation) {
+	names := make(map[string]string)
+	for _, qt := range q.tables {
+		names[qt.ref()] = qt.fullName()
+	}
+	relations = make([]*QueryRelation, 0)
+	for _, qr := range q.relations {
+		table1, table2 := qr.table1, qr.table2
+		if name, ok := names[table1]; ok {
+			table1 = name
+		}
+		if name, ok := names[table2]; ok {
+			table2 = name
+		}
+		relations = append(relations, &QueryRelation{table1, qr.column1, table2, qr.column2})
+	}
+	return
+}
+
+func findRelation(relations []*QueryRelation, tableName string, tableColumn string) (rightTableName string, rightTableColumn string, err error) {
+	for _, qr := range relations {
+		if qr.table1 == tableName && qr.column1 == tableColumn {
+			rightTableName = qr.table2
+			rightTableColumn = qr.column2
+			break
+		}
+		if qr.table2 == tableName && qr.column2 == tableColumn {
+			rightTableName = qr.table1
+			rightTableColumn = qr.column1
+			break
+		}
+	}
+	if rightTableColumn == "" {
+		return "", "", fmt.Errorf("Cannot find relation for column '%s' of table '%s'", tableColumn, tableName)
+	}
+	return
+}
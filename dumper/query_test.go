@@ -0,0 +1,1131 @@
+package dumper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var typicalQuery = &Query{
+	tables: []*QueryTable{
+		{"routes", []string{"id", "name"}, "", ""},
+		{"stations", []string{"id", "sname"}, "", ""},
+		{"stations_for_routes", []string{"station_id", "route_id", "ord"}, "", ""},
+	},
+	relations: []*QueryRelation{
+		{"routes", "id", "stations_for_routes", "route_id"},
+		{"stations", "id", "stations_for_routes", "station_id"},
+	},
+	primaryInterval: []int64{1000, 2000},
+}
+
+var aliasedQuery = &Query{
+	tables: []*QueryTable{
+		{"orders", []string{"id", "buyer_id", "seller_id"}, "", ""},
+		{"users", []string{"id", "name"}, "buyers", ""},
+		{"users", []string{"id", "email"}, "sellers", ""},
+	},
+	relations: []*QueryRelation{
+		{"buyers", "id", "orders", "buyer_id"},
+		{"sellers", "id", "orders", "seller_id"},
+	},
+	primaryInterval: []int64{1, 10},
+}
+
+type EmptyWriter struct {
+}
+
+func (w *EmptyWriter) WriteRows(_ context.Context, _ string, _ []string, _ []*map[string]interface{}) (err error) {
+	return nil
+}
+
+func (w *EmptyWriter) WriteDDL(_ context.Context, _ string, _ string) (err error) {
+	return nil
+}
+
+func TestQueryResult(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("DESCRIBE `stations`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("DESCRIBE `stations_for_routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("station_id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("SELECT (.+) FROM `routes` WHERE `routes`.`id` BETWEEN \\? AND \\?").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	mock.ExpectQuery("SELECT (.+) FROM `stations` WHERE `stations`.`id` IN (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	mock.ExpectQuery("SELECT (.+) FROM `stations_for_routes` WHERE `stations_for_routes`.`route_id` IN (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"station_id", "route_id", "ord"}))
+
+	err = typicalQuery.dump(context.Background(), sqlxDB, &EmptyWriter{}, &dumpOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+}
+
+// DDLRecorderWriter records names of tables in order of writing DDL
+type DDLRecorderWriter struct {
+	EmptyWriter
+	ddlTables []string
+}
+
+func (w *DDLRecorderWriter) WriteDDL(_ context.Context, tableName string, _ string) (err error) {
+	w.ddlTables = append(w.ddlTables, tableName)
+	return nil
+}
+
+func TestQueryResultDDLInOrderOfTables(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		mockDB, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+		sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+		mock.MatchExpectationsInOrder(false)
+		for _, table := range []string{"orders", "users"} {
+			mock.ExpectQuery("DESCRIBE `" + table + "`").
+				WillReturnRows(
+					sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+				)
+		}
+		mock.ExpectQuery("SELECT (.+)").
+			WithArgs(1, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		writer := &DDLRecorderWriter{}
+		err = aliasedQuery.dump(context.Background(), sqlxDB, writer, &dumpOptions{combined: true})
+		mockDB.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual([]string{"orders", "users"}, writer.ddlTables) {
+			t.Fatalf("Expected DDL in order of tables, but got %v", writer.ddlTables)
+		}
+	}
+}
+
+func TestQueryResultIntervalError(t *testing.T) {
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"some_table", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{},
+	}
+
+	err := simpleQuery.dump(context.Background(), nil, &SimpleWriter{}, &dumpOptions{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestQueryResultSingleQueryError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("DESCRIBE `stations`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("DESCRIBE `stations_for_routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("station_id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("SELECT (.+) FROM `routes` WHERE `routes`.`id` BETWEEN \\? AND \\?").
+		WithArgs(1000, 2000).
+		WillReturnError(fmt.Errorf("Some error"))
+
+	err = typicalQuery.dump(context.Background(), sqlxDB, &EmptyWriter{}, &dumpOptions{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestQueryResultRelationError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	queryWithBadRelations := &Query{
+		tables: []*QueryTable{
+			{"routes", []string{"id", "name"}, "", ""},
+			{"stations", []string{"id", "sname"}, "", ""},
+		},
+		relations: []*QueryRelation{
+			{"routes", "id", "stations_for_routes", "route_id"},
+		},
+		primaryInterval: []int64{1000, 2000},
+	}
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("DESCRIBE `stations`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("SELECT (.+) FROM `routes` WHERE `routes`.`id` BETWEEN \\? AND \\?").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	err = queryWithBadRelations.dump(context.Background(), sqlxDB, &EmptyWriter{}, &dumpOptions{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestQueryResultDDLError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"some_table", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 2},
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM `some_table`").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnError(fmt.Errorf("Some error"))
+
+	err = simpleQuery.dump(context.Background(), sqlxDB, &EmptyWriter{}, &dumpOptions{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestQueryResultCombined(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("DESCRIBE `stations`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("DESCRIBE `stations_for_routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("station_id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("SELECT (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	err = typicalQuery.dump(context.Background(), sqlxDB, &EmptyWriter{}, &dumpOptions{combined: true})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+}
+
+func TestQueryResultFileError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"some_table", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 2},
+	}
+
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	fw := &TestFileErrorWriter{}
+	writer := NewSqlWriter(fw, "result.sql", "")
+
+	err = simpleQuery.dump(context.Background(), sqlxDB, writer, &dumpOptions{combined: true})
+	if err == nil {
+		t.Errorf("Expected error by file writer, but got nil")
+		return
+	}
+}
+
+func TestSelectAndWriteCombinedFileError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"some_table", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 2},
+	}
+
+	mock.ExpectQuery("SELECT (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "some"),
+		)
+
+	fw := &TestFileErrorWriter{}
+	writer := NewSqlWriter(fw, "result.sql", "")
+
+	err = simpleQuery.selectAndWrite(context.Background(), sqlxDB, writer, true, nil, nil, &dumpOptions{jobs: 1})
+	if err == nil {
+		t.Errorf("Expected error by file writer, but got nil")
+		return
+	}
+}
+
+func TestSelectAndWriteFileError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"some_table", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 2},
+	}
+
+	mock.ExpectQuery("SELECT (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "some"),
+		)
+
+	fw := &TestFileErrorWriter{}
+	writer := NewSqlWriter(fw, "result.sql", "")
+
+	err = simpleQuery.selectAndWrite(context.Background(), sqlxDB, writer, false, nil, nil, &dumpOptions{jobs: 1})
+	if err == nil {
+		t.Errorf("Expected error by file writer, but got nil")
+		return
+	}
+}
+
+func TestQueryResultCombinedError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"some_table", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 2},
+	}
+
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	mock.ExpectQuery("SELECT (.+)").
+		WithArgs(1000, 2000).
+		WillReturnError(fmt.Errorf("Some error"))
+
+	err = simpleQuery.dump(context.Background(), sqlxDB, &EmptyWriter{}, &dumpOptions{combined: true})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestDbSelect(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	columns := []string{"id", "name"}
+
+	mock.ExpectQuery("SELECT (.+) FROM some_table WHERE id BETWEEN \\? AND \\?").
+		WithArgs(10, 20).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "name1").AddRow(2, "name2"))
+
+	resultsMaps, err := dbSelect(context.Background(), sqlxDB, "SELECT id, name FROM some_table WHERE id BETWEEN ? AND ?", 10, 20)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+
+	expectedResultsMaps := []*map[string]interface{}{
+		{"id": 1, "name": "name1"},
+		{"id": 2, "name": "name2"},
+	}
+
+	if !reflect.DeepEqual(expectedResultsMaps, resultsMaps) {
+		t.Errorf("Unexpected results")
+		return
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM some_table WHERE id BETWEEN \\? AND \\?").
+		WithArgs(10, 20).
+		WillReturnError(fmt.Errorf("Some error"))
+
+	_, err = dbSelect(context.Background(), sqlxDB, "SELECT id, name FROM some_table WHERE id BETWEEN ? AND ?", 10, 20)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestToSqlForSingleTable(t *testing.T) {
+	sql := typicalQuery.toSqlForSingleTable(typicalQuery.tables[0])
+	expected := "SELECT `routes`.`id`, `routes`.`name`\n" +
+		"FROM `routes`\n" +
+		"WHERE `routes`.`id` BETWEEN ? AND ?"
+	if sql != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, sql)
+	}
+}
+
+func TestToSqlForRelation(t *testing.T) {
+	sql, _ := typicalQuery.toSqlForRelation(typicalQuery.tables[1])
+	expected := "SELECT `stations`.`id`, `stations`.`sname`\n" +
+		"FROM `stations`\n" +
+		"WHERE `stations`.`id` IN\n" +
+		"(\n" +
+		"SELECT `stations_for_routes`.`station_id`\n" +
+		"FROM `routes`, `stations_for_routes`\n" +
+		"WHERE (`routes`.`id` BETWEEN ? AND ?) AND (`routes`.`id` = `stations_for_routes`.`route_id`)\n" +
+		")"
+	if sql != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, sql)
+	}
+
+	sql2, err := typicalQuery.toSqlForRelation(typicalQuery.tables[0])
+	if err == nil {
+		t.Errorf("Expected error, but got query: %s", sql2)
+	}
+}
+
+func TestToSqlForRelationWithAliases(t *testing.T) {
+	sql, err := aliasedQuery.toSqlForRelation(aliasedQuery.tables[2])
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	expected := "SELECT `sellers`.`id`, `sellers`.`name`, `sellers`.`email`\n" +
+		"FROM `users` AS `sellers`\n" +
+		"WHERE `sellers`.`id` IN\n" +
+		"(\n" +
+		"SELECT `orders`.`seller_id`\n" +
+		"FROM `orders`, `users` AS `buyers`\n" +
+		"WHERE (`orders`.`id` BETWEEN ? AND ?) AND (`buyers`.`id` = `orders`.`buyer_id`)\n" +
+		")"
+	if sql != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, sql)
+	}
+}
+
+func TestToSqlForCombinedRowsWithAliases(t *testing.T) {
+	sql := aliasedQuery.toSqlForCombinedRows()
+	expected := "SELECT `orders`.`id` AS `orders.id`, `orders`.`buyer_id` AS `orders.buyer_id`, "
+	expected += "`orders`.`seller_id` AS `orders.seller_id`, "
+	expected += "`buyers`.`id` AS `buyers.id`, `buyers`.`name` AS `buyers.name`, "
+	expected += "`sellers`.`id` AS `sellers.id`, `sellers`.`email` AS `sellers.email`\n"
+	expected += "FROM `orders`, `users` AS `buyers`, `users` AS `sellers`\n"
+	expected += "WHERE (`buyers`.`id` = `orders`.`buyer_id`) AND "
+	expected += "(`sellers`.`id` = `orders`.`seller_id`) AND (`orders`.`id` BETWEEN ? AND ?)"
+	if sql != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, sql)
+	}
+}
+
+func TestToSqlForQualifiedTables(t *testing.T) {
+	query, err := ParseRequest("core.customers:id,name;billing.invoices:id,customer_id;\"my.table\"@`t``1`:id", "1-2",
+		"core.customers.id=billing.invoices.customer_id;`t``1`.id=billing.invoices.id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	sql, err := query.toSqlForRelation(query.tables[2])
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "SELECT `t``1`.`id`\n" +
+		"FROM `my.table` AS `t``1`\n" +
+		"WHERE `t``1`.`id` IN\n" +
+		"(\n" +
+		"SELECT `billing`.`invoices`.`id`\n" +
+		"FROM `core`.`customers`, `billing`.`invoices`\n" +
+		"WHERE (`core`.`customers`.`id` BETWEEN ? AND ?) AND (`core`.`customers`.`id` = `billing`.`invoices`.`customer_id`)\n" +
+		")"
+	if sql != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, sql)
+	}
+}
+
+func TestQueryResultWithAliases(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("DESCRIBE `orders`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
+				AddRow("id", "bigint(20)", "NO", "PRI", nil, "").
+				AddRow("buyer_id", "bigint(20)", "NO", "MUL", nil, "").
+				AddRow("seller_id", "bigint(20)", "NO", "MUL", nil, ""),
+		)
+
+	mock.ExpectQuery("DESCRIBE `users`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
+				AddRow("id", "bigint(20)", "NO", "PRI", nil, "").
+				AddRow("name", "varchar(100)", "NO", "", nil, "").
+				AddRow("email", "varchar(100)", "NO", "", nil, ""),
+		)
+
+	mock.ExpectQuery("SELECT (.+) FROM `orders` WHERE `orders`.`id` BETWEEN \\? AND \\?").
+		WithArgs(1, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "buyer_id", "seller_id"}).AddRow(1, 5, 5))
+
+	mock.ExpectQuery("SELECT (.+) FROM `users` AS `buyers` WHERE `buyers`.`id` IN (.+)").
+		WithArgs(1, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(5, "Name", "mail"))
+
+	mock.ExpectQuery("SELECT (.+) FROM `users` AS `sellers` WHERE `sellers`.`id` IN (.+)").
+		WithArgs(1, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(5, "Name", "mail"))
+
+	fw := NewMemoryFileWriter()
+	writer := NewSqlWriter(fw, "result.sql", "")
+	err = aliasedQuery.dump(context.Background(), sqlxDB, writer, &dumpOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+
+	result := fw.Contents("result.sql")
+	if strings.Count(result, "CREATE TABLE `users`") != 1 {
+		t.Errorf("Expected one DDL for table users, got:\n%s", result)
+	}
+	if strings.Count(result, "INSERT INTO `users`") != 1 {
+		t.Errorf("Expected one row for table users, got:\n%s", result)
+	}
+	if !strings.Contains(result, "CONSTRAINT `fk_seller_id` FOREIGN KEY (`seller_id`) REFERENCES `users` (`id`)") {
+		t.Errorf("Expected FK to physical table, got:\n%s", result)
+	}
+}
+
+func TestSelectAndWriteWithMasking(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"users", []string{"id", "email"}, "buyers", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 2},
+	}
+
+	mock.ExpectQuery("SELECT (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "john@corp.com"))
+
+	mock.ExpectQuery("SELECT (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"buyers.id", "buyers.email"}).AddRow(1, "john@corp.com"))
+
+	masker := NewMasker([]*MaskingRule{{"users", "email", "null", ""}}, "")
+	fw := NewMemoryFileWriter()
+	writer := NewSqlWriter(fw, "result.sql", "")
+	err = simpleQuery.selectAndWrite(context.Background(), sqlxDB, writer, false, nil, masker, &dumpOptions{jobs: 1})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	csvFw := NewMemoryFileWriter()
+	err = simpleQuery.selectAndWrite(context.Background(), sqlxDB, NewCsvWriter(csvFw, "result.csv", "", ","), true, nil, masker, &dumpOptions{jobs: 1})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+
+	expected := "INSERT INTO `users` (`id`, `email`) VALUES (1, NULL);\n"
+	if fw.Contents("result.sql") != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, fw.Contents("result.sql"))
+	}
+	expectedCsv := "\"buyers.id\",\"buyers.email\"\r\n1,NULL\r\n"
+	if csvFw.Contents("result.csv") != expectedCsv {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expectedCsv, csvFw.Contents("result.csv"))
+	}
+}
+
+func TestExpandMaskingRules(t *testing.T) {
+	query := &Query{
+		tables: []*QueryTable{
+			{"orders", []string{"id", "buyer_id"}, "", ""},
+			{"users", []string{"id"}, "buyers", ""},
+			{"payments", []string{"order_id", "payer_id"}, "", ""},
+		},
+		relations: []*QueryRelation{
+			{"buyers", "id", "orders", "buyer_id"},
+			{"payments", "payer_id", "orders", "buyer_id"},
+			{"payments", "order_id", "orders", "id"},
+		},
+		primaryInterval: []int64{1, 10},
+	}
+	rules := query.expandMaskingRules([]*MaskingRule{
+		{"users", "id", "pseudo", ""},
+		{"orders", "id", "hash", ""},
+	})
+	expected := []*MaskingRule{
+		{"users", "id", "pseudo", ""},
+		{"orders", "id", "hash", ""},
+		{"orders", "buyer_id", "pseudo", ""},
+		{"payments", "payer_id", "pseudo", ""},
+	}
+	if !reflect.DeepEqual(expected, rules) {
+		t.Errorf("EXP %v GOT %v", expected, rules)
+	}
+}
+
+func TestSelectAndWriteConcurrently(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectQuery("SELECT (.+) FROM `stations_for_routes` WHERE (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"station_id", "route_id", "ord"}).AddRow(1, 1000, 0))
+
+	mock.ExpectQuery("SELECT (.+) FROM `stations` WHERE (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sname"}).AddRow(1, "Station"))
+
+	mock.ExpectQuery("SELECT (.+) FROM `routes` WHERE (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1000, "Route"))
+
+	fw := NewMemoryFileWriter()
+	writer := NewSqlWriter(fw, "result.sql", "")
+	err = typicalQuery.selectAndWrite(context.Background(), sqlxDB, writer, false, nil, nil, &dumpOptions{jobs: 3})
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	expected := "INSERT INTO `routes` (`id`, `name`) VALUES (1000, 'Route');\n" +
+		"INSERT INTO `stations` (`id`, `sname`) VALUES (1, 'Station');\n" +
+		"INSERT INTO `stations_for_routes` (`station_id`, `route_id`, `ord`) VALUES (1, 1000, 0);\n"
+	if fw.Contents("result.sql") != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, fw.Contents("result.sql"))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %s", err)
+	}
+}
+
+func TestSelectAndWriteRestoresKeysOfWrittenRows(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	// Rows of stations are selected again to remember their keys, rows of other tables are not deduplicated
+	mock.ExpectQuery("SELECT (.+) FROM `stations` WHERE (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sname"}).AddRow(1, "Station"))
+
+	mock.ExpectQuery("SELECT (.+) FROM `routes` WHERE (.+)").
+		WithArgs(2001, 3000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2001, "Route"))
+
+	mock.ExpectQuery("SELECT (.+) FROM `stations` WHERE (.+)").
+		WithArgs(2001, 3000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sname"}).AddRow(1, "Station").AddRow(2, "Other station"))
+
+	mock.ExpectQuery("SELECT (.+) FROM `stations_for_routes` WHERE (.+)").
+		WithArgs(2001, 3000).
+		WillReturnRows(sqlmock.NewRows([]string{"station_id", "route_id", "ord"}).AddRow(1, 2001, 0).AddRow(2, 2001, 1))
+
+	checkpoint := &Checkpoint{Tables: map[string]*TableProgress{
+		"routes":              {2000, 0},
+		"stations":            {2000, 0},
+		"stations_for_routes": {2000, 0},
+	}}
+	deduplicator := NewRowsDeduplicator(map[string][]string{"stations": {"id"}}, map[string]bool{}, 0, "")
+	defer deduplicator.Close()
+	fw := NewMemoryFileWriter()
+	writer := NewSqlWriter(fw, "result.sql", "")
+	writtenQuery := &Query{typicalQuery.tables, typicalQuery.relations, []int64{1000, 2000}}
+	err = writtenQuery.selectAndWrite(context.Background(), sqlxDB, writer, false, deduplicator, nil, &dumpOptions{jobs: 1, checkpoint: checkpoint})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	nextQuery := &Query{typicalQuery.tables, typicalQuery.relations, []int64{2001, 3000}}
+	err = nextQuery.selectAndWrite(context.Background(), sqlxDB, writer, false, deduplicator, nil, &dumpOptions{jobs: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := "INSERT INTO `routes` (`id`, `name`) VALUES (2001, 'Route');\n" +
+		"INSERT INTO `stations` (`id`, `sname`) VALUES (2, 'Other station');\n" +
+		"INSERT INTO `stations_for_routes` (`station_id`, `route_id`, `ord`) VALUES (1, 2001, 0);\n" +
+		"INSERT INTO `stations_for_routes` (`station_id`, `route_id`, `ord`) VALUES (2, 2001, 1);\n"
+	if fw.Contents("result.sql") != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, fw.Contents("result.sql"))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %s", err)
+	}
+}
+
+func TestSelectAndWriteConcurrentlyError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectQuery("SELECT (.+) FROM `routes` WHERE (.+)").
+		WithArgs(1000, 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1000, "Route"))
+
+	mock.ExpectQuery("SELECT (.+) FROM `stations` WHERE (.+)").
+		WithArgs(1000, 2000).
+		WillReturnError(fmt.Errorf("Some error"))
+
+	err = typicalQuery.selectAndWrite(context.Background(), sqlxDB, &EmptyWriter{}, false, nil, nil, &dumpOptions{jobs: 2})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestSelectAndWriteCancelled(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fw := NewMemoryFileWriter()
+	err = typicalQuery.selectAndWrite(ctx, sqlxDB, NewSqlWriter(fw, "result.sql", ""), false, nil, nil, &dumpOptions{jobs: 1})
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, but got %v", err)
+	}
+	if len(fw.Filenames()) != 0 {
+		t.Errorf("Expected no written files, but got %d", len(fw.Filenames()))
+	}
+}
+
+func TestSelectAndWriteQueryTimeout(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	var simpleQuery = &Query{
+		tables: []*QueryTable{
+			{"some_table", []string{"id"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 2},
+	}
+
+	mock.ExpectQuery("SELECT (.+)").
+		WithArgs(1, 2).
+		WillDelayFor(time.Second).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id"}).AddRow(1),
+		)
+
+	options := &dumpOptions{jobs: 1, queryTimeout: 10 * time.Millisecond}
+	err = simpleQuery.selectAndWrite(context.Background(), sqlxDB, &EmptyWriter{}, true, nil, nil, options)
+	if err == nil {
+		t.Errorf("Expected error by query timeout, but got nil")
+		return
+	}
+	if !strings.Contains(err.Error(), "Query exceeded timeout 10ms") {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestToSqlForCombinedRows(t *testing.T) {
+	sql := typicalQuery.toSqlForCombinedRows()
+	expected := "SELECT `routes`.`id` AS `routes.id`, `routes`.`name` AS `routes.name`, "
+	expected += "`stations`.`id` AS `stations.id`, `stations`.`sname` AS `stations.sname`, "
+	expected += "`stations_for_routes`.`station_id` AS `stations_for_routes.station_id`, "
+	expected += "`stations_for_routes`.`route_id` AS `stations_for_routes.route_id`, "
+	expected += "`stations_for_routes`.`ord` AS `stations_for_routes.ord`\n"
+	expected += "FROM `routes`, `stations`, `stations_for_routes`\n"
+	expected += "WHERE (`routes`.`id` = `stations_for_routes`.`route_id`) AND "
+	expected += "(`stations`.`id` = `stations_for_routes`.`station_id`) AND (`routes`.`id` BETWEEN ? AND ?)"
+	if sql != expected {
+		t.Errorf("EXP:\n%s\nGOT:\n%s\n", expected, sql)
+	}
+}
+
+func TestToDDLError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnError(fmt.Errorf("Some error"))
+
+	_, err = typicalQuery.toDDL(context.Background(), sqlxDB)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestToDDLForQualifiedTables(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	query, err := ParseRequest("core.customers:id;billing.invoices:id,customer_id", "1-2", "core.customers.id=billing.invoices.customer_id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	mock.ExpectQuery("DESCRIBE `core`.`customers`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("DESCRIBE `billing`.`invoices`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
+				AddRow("id", "bigint(20)", "NO", "PRI", nil, "").
+				AddRow("customer_id", "bigint(20)", "NO", "MUL", nil, ""),
+		)
+
+	ddls, err := query.toDDL(context.Background(), sqlxDB)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := map[string]string{
+		"core.customers": "CREATE DATABASE IF NOT EXISTS `core`;\n" +
+			"CREATE TABLE `core`.`customers` (\n" +
+			"    `id` bigint(20) NOT NULL,\n" +
+			"    PRIMARY KEY (`id`)\n" +
+			");",
+		"billing.invoices": "CREATE DATABASE IF NOT EXISTS `billing`;\n" +
+			"CREATE TABLE `billing`.`invoices` (\n" +
+			"    `id` bigint(20) NOT NULL,\n" +
+			"    `customer_id` bigint(20) NOT NULL,\n" +
+			"    PRIMARY KEY (`id`),\n" +
+			"    INDEX `customer_id` (`customer_id`),\n" +
+			"    CONSTRAINT `fk_customer_id` FOREIGN KEY (`customer_id`) REFERENCES `core`.`customers` (`id`) ON DELETE CASCADE\n" +
+			");",
+	}
+	if !reflect.DeepEqual(expected, ddls) {
+		t.Errorf("EXP:\n%v\nGOT:\n%v\n", expected, ddls)
+	}
+}
+
+func TestToDDLError2(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("An error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("OTHER_FIELD", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+
+	_, err = typicalQuery.toDDL(context.Background(), sqlxDB)
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestMakeDDLFromTableDescriptionError(t *testing.T) {
+	_, err := makeDDLFromTableDescription("", []TableColumnDDL{}, []string{}, []*QueryRelation{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func TestMakeDDLFromTableDescription(t *testing.T) {
+	tableDescribtion := []TableColumnDDL{
+		{"id", "bigint(20)", "NO", "PRI", sql.NullString{String: "", Valid: false}, ""},
+		{"id2", "bigint(20)", "YES", "MUL", sql.NullString{String: "", Valid: true}, ""},
+		{"id3", "bigint(20)", "YES", "UNI", sql.NullString{String: "0", Valid: true}, ""},
+		{"id4", "varchar(255)", "YES", "PRI", sql.NullString{String: "", Valid: false}, ""},
+	}
+
+	relations := []*QueryRelation{
+		{"some_table", "id2", "other_table", "id"},
+	}
+
+	ddl, err := makeDDLFromTableDescription("some_table", tableDescribtion, []string{"id", "id2", "id3", "no"}, relations)
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+		return
+	}
+	expectedDDL := "CREATE TABLE `some_table` (\n" +
+		"    `id` bigint(20) NOT NULL,\n" +
+		"    `id2` bigint(20) NULL DEFAULT '',\n" +
+		"    `id3` bigint(20) NULL DEFAULT '0',\n" +
+		"    PRIMARY KEY (`id`),\n" +
+		"    INDEX `id2` (`id2`),\n" +
+		"    UNIQUE INDEX `id3` (`id3`),\n" +
+		"    CONSTRAINT `fk_id2` FOREIGN KEY (`id2`) REFERENCES `other_table` (`id`) ON DELETE CASCADE\n" +
+		");"
+	if ddl != expectedDDL {
+		t.Errorf("Expected DDL\n%s\nGOT:\n%s\n", expectedDDL, ddl)
+		return
+	}
+}
+
+func TestTqlPartForSelectColumns(t *testing.T) {
+	sql := typicalQuery.tables[0].sqlPartForSelectColumns()
+	expected := "`routes`.`id`, `routes`.`name`"
+	if sql != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, sql)
+	}
+}
+
+func TestSqlTable(t *testing.T) {
+	sql := sqlTable("some_table")
+	expected := "`some_table`"
+	if sql != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, sql)
+	}
+}
+
+func TestSqlTableEscaping(t *testing.T) {
+	sql := sqlTable("some`table")
+	expected := "`some``table`"
+	if sql != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, sql)
+	}
+}
+
+func TestSqlName(t *testing.T) {
+	sql := sqlName("core.\"my.table\"")
+	expected := "`core`.`my.table`"
+	if sql != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, sql)
+	}
+}
+
+func TestSqlColumn(t *testing.T) {
+	sql := sqlColumn("some_column")
+	expected := "`some_column`"
+	if sql != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, sql)
+	}
+}
+
+func TestSqlTableAndColumn(t *testing.T) {
+	sql := sqlTableAndColumn("some_table", "some_column")
+	expected := "`some_table`.`some_column`"
+	if sql != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, sql)
+	}
+
+	sql = sqlTableAndColumn("core.customers", "col`1")
+	expected = "`core`.`customers`.`col``1`"
+	if sql != expected {
+		t.Errorf("EXPECTED '%s' GOT '%s'", expected, sql)
+	}
+}
+
+type testToSqlSubQueryForRelationInput struct {
+	query                   *Query
+	mainTable               *QueryTable
+	expectedSubquery        string
+	expectedLeftTableColumn string
+	expectedErr             bool
+}
+
+var testsToSqlSubQueryForRelation = []testToSqlSubQueryForRelationInput{
+	{
+		query:     typicalQuery,
+		mainTable: &QueryTable{"stations", []string{"id", "sname"}, "", ""},
+		expectedSubquery: "SELECT `stations_for_routes`.`station_id`\n" +
+			"FROM `routes`, `stations`, `stations_for_routes`\n" +
+			"WHERE (`routes`.`id` BETWEEN ? AND ?) AND (`routes`.`id` = `stations_for_routes`.`route_id`)",
+		expectedLeftTableColumn: "`stations`.`id`",
+	},
+	{
+		query: &Query{
+			tables: []*QueryTable{
+				{"routes", []string{"id", "name"}, "", ""},
+				{"stations", []string{"id", "sname"}, "", ""},
+				{"stations_for_routes", []string{"station_id", "route_id", "ord"}, "", ""},
+			},
+			relations: []*QueryRelation{
+				{"routes", "id", "stations_for_routes", "route_id"},
+				{"stations_for_routes", "station_id", "stations", "id"}, // inverted
+			},
+			primaryInterval: []int64{1000, 2000},
+		},
+		mainTable: &QueryTable{"stations", []string{"id", "sname"}, "", ""},
+		expectedSubquery: "SELECT `stations_for_routes`.`station_id`\n" +
+			"FROM `routes`, `stations`, `stations_for_routes`\n" +
+			"WHERE (`routes`.`id` BETWEEN ? AND ?) AND (`routes`.`id` = `stations_for_routes`.`route_id`)",
+		expectedLeftTableColumn: "`stations`.`id`",
+	},
+	{
+		query:       typicalQuery,
+		mainTable:   &QueryTable{"routes", []string{"id", "name"}, "", ""},
+		expectedErr: true,
+	},
+	{
+		query:       typicalQuery,
+		mainTable:   &QueryTable{"people", []string{"id"}, "", ""},
+		expectedErr: true,
+	},
+}
+
+func TestToSqlSubQueryForRelation(t *testing.T) {
+	for _, input := range testsToSqlSubQueryForRelation {
+		subquery, leftTableColumn, err := input.query.toSqlSubQueryForRelation(input.mainTable)
+		if err != nil && !input.expectedErr {
+			t.Errorf("Unexpected error: %s", err)
+			continue
+		}
+		if subquery != input.expectedSubquery || leftTableColumn != input.expectedLeftTableColumn {
+			t.Errorf("FOR %s WITH MAIN TABLE %s \n EXP %s WITH %s\n GOT %s WITH %s\n",
+				convertQueryToString(input.query),
+				input.mainTable.name,
+				input.expectedSubquery,
+				input.expectedLeftTableColumn,
+				subquery,
+				leftTableColumn,
+			)
+		}
+	}
+}
+
+func TestFindRelationError(t *testing.T) {
+	relations := []*QueryRelation{
+		{"some_table", "id2", "other_table", "id"},
+	}
+	_, _, err := findRelation(relations, "other_table", "other_column")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+		return
+	}
+}
+
+func convertQueryToString(q *Query) string {
+	return fmt.Sprintf("%s\n%v\n%s\n",
+		convertQtsToString(q.tables),
+		q.primaryInterval,
+		convertQrsToString(q.relations),
+	)
+}
@@ -0,0 +1,239 @@
+package dumper
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseRequest parses input strings into query definitions
+func ParseRequest(tablesPart string, intervalPart string, relationsPart string) (query *Query, err error) {
+	tables, err := parseTablesPart(tablesPart)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := parseIntervalPart(intervalPart)
+	if err != nil {
+		return nil, err
+	}
+	relations, err := parseRelationsPart(relationsPart)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{
+		tables:          tables,
+		relations:       relations,
+		primaryInterval: interval,
+	}, nil
+}
+
+func parseTablesPart(tablesPart string) (tables []*QueryTable, err error) {
+	if tablesPart == "" {
+		return nil, fmt.Errorf("Tables part is empty")
+	}
+	p, err := newRequestParser(tablesPart)
+	if err != nil {
+		return nil, err
+	}
+	tables = make([]*QueryTable, 0)
+	for {
+		queryTable, err := p.parseTableDefinition()
+		if err != nil {
+			return nil, err
+		}
+		for _, qt := range tables {
+			if qt.ref() == queryTable.ref() {
+				return nil, fmt.Errorf("Table '%s' is defined twice. Use aliases in format 'table@alias'", queryTable.ref())
+			}
+		}
+		tables = append(tables, queryTable)
+		if p.end() {
+			return tables, nil
+		}
+		if err = p.expectSeparator(';'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func parseIntervalPart(intervalPart string) (interval []int64, err error) {
+	interval = make([]int64, 2)
+	intervalParts := strings.Split(intervalPart, "-")
+	if len(intervalParts) != 2 {
+		return nil, fmt.Errorf("Interval definition should be in format 'start-end'. Got: %s", intervalPart)
+	}
+	startValue, err := strconv.ParseInt(intervalParts[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	endValue, err := strconv.ParseInt(intervalParts[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	interval[0] = startValue
+	interval[1] = endValue
+	return interval, err
+}
+
+func parseRelationsPart(relationsPart string) (relations []*QueryRelation, err error) {
+	relations = make([]*QueryRelation, 0)
+	if len(relationsPart) == 0 {
+		return relations, nil
+	}
+	p, err := newRequestParser(relationsPart)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		queryRelation, err := p.parseRelationDefinition()
+		if err != nil {
+			return nil, err
+		}
+		relations = append(relations, queryRelation)
+		if p.end() {
+			return relations, nil
+		}
+		if err = p.expectSeparator(';'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// requestParser reads definitions of tables and relations from tokens of request
+type requestParser struct {
+	part   string
+	tokens []*token
+	i      int
+}
+
+func newRequestParser(part string) (*requestParser, error) {
+	tokens, err := tokenize(part)
+	if err != nil {
+		return nil, err
+	}
+	return &requestParser{part, tokens, 0}, nil
+}
+
+func (p *requestParser) end() bool {
+	return p.i >= len(p.tokens)
+}
+
+func (p *requestParser) peek() *token {
+	if p.end() {
+		return nil
+	}
+	return p.tokens[p.i]
+}
+
+// column returns position of current token or position after the end of part
+func (p *requestParser) column() int {
+	if p.end() {
+		return len([]rune(p.part)) + 1
+	}
+	return p.tokens[p.i].column
+}
+
+func (p *requestParser) errorf(format string, args ...interface{}) error {
+	return positionError(p.part, p.column(), format, args...)
+}
+
+// skipSeparator moves to the next token if current token is the separator
+func (p *requestParser) skipSeparator(separator rune) bool {
+	if p.peek().isSeparator(separator) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *requestParser) expectSeparator(separator rune) error {
+	if !p.skipSeparator(separator) {
+		return p.errorf("Expected '%c', but got %s", separator, p.peek())
+	}
+	return nil
+}
+
+// expectIdentifier returns the current non-empty identifier. What describes expected identifier for error.
+func (p *requestParser) expectIdentifier(what string) (string, error) {
+	t := p.peek()
+	if t == nil || t.separator != 0 || t.value == "" {
+		return "", p.errorf("Expected %s, but got %s", what, t)
+	}
+	p.i++
+	return t.value, nil
+}
+
+// parseTableDefinition reads definition in format 'schema.table@alias:column1,column2,...', schema and alias are optional
+func (p *requestParser) parseTableDefinition() (*QueryTable, error) {
+	queryTable := &QueryTable{columns: make([]string, 0)}
+	name, err := p.expectIdentifier("name of table")
+	if err != nil {
+		return nil, err
+	}
+	queryTable.name = name
+	if p.skipSeparator('.') {
+		queryTable.schema = name
+		queryTable.name, err = p.expectIdentifier("name of table")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.skipSeparator('@') {
+		queryTable.alias, err = p.expectIdentifier("alias of table")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !p.skipSeparator(':') {
+		return nil, p.errorf("Table definition should be in format 'table:column1,column2,...', but got %s", p.peek())
+	}
+	for {
+		column, err := p.expectIdentifier("name of column")
+		if err != nil {
+			return nil, err
+		}
+		queryTable.columns = append(queryTable.columns, column)
+		if !p.skipSeparator(',') {
+			return queryTable, nil
+		}
+	}
+}
+
+// parseRelationDefinition reads definition in format 'table1.column1=table2.column2'
+func (p *requestParser) parseRelationDefinition() (*QueryRelation, error) {
+	table1, column1, err := p.parseRelationSide()
+	if err != nil {
+		return nil, err
+	}
+	if !p.skipSeparator('=') {
+		return nil, p.errorf("Relation definition should be in format 'table1.column1=table2.column2', but got %s", p.peek())
+	}
+	table2, column2, err := p.parseRelationSide()
+	if err != nil {
+		return nil, err
+	}
+	return &QueryRelation{table1, column1, table2, column2}, nil
+}
+
+// parseRelationSide reads column of table in format 'table.column' or 'schema.table.column'.
+// Table is returned as reference in format of request.
+func (p *requestParser) parseRelationSide() (table string, column string, err error) {
+	names := make([]string, 0)
+	for {
+		name, err := p.expectIdentifier("name of table or column")
+		if err != nil {
+			return "", "", err
+		}
+		names = append(names, name)
+		if len(names) == 3 || !p.skipSeparator('.') {
+			break
+		}
+	}
+	switch len(names) {
+	case 2:
+		return formatIdentifier(names[0]), names[1], nil
+	case 3:
+		return qualifiedName(names[0], names[1]), names[2], nil
+	}
+	return "", "", p.errorf("Relation definition should be in format 'table1.column1=table2.column2', but got %s", p.peek())
+}
@@ -1,4 +1,4 @@
-package main
+package dumper
 
 import (
 	"fmt"
@@ -18,25 +18,89 @@ var testsTable = []testTableTripl{
 	{
 		tablesPart: "routes:id,name;stations:id,sname;stations_for_routes:station_id,route_id,ord",
 		expected: []*QueryTable{
-			{"routes", []string{"id", "name"}},
-			{"stations", []string{"id", "sname"}},
-			{"stations_for_routes", []string{"station_id", "route_id", "ord"}},
+			{"routes", []string{"id", "name"}, "", ""},
+			{"stations", []string{"id", "sname"}, "", ""},
+			{"stations_for_routes", []string{"station_id", "route_id", "ord"}, "", ""},
 		},
 	},
 	{
 		tablesPart: "routes:id,name",
 		expected: []*QueryTable{
-			{"routes", []string{"id", "name"}},
+			{"routes", []string{"id", "name"}, "", ""},
+		},
+	},
+	{
+		tablesPart: "orders:id,buyer_id,seller_id;users@buyers:id,name;users@sellers:id",
+		expected: []*QueryTable{
+			{"orders", []string{"id", "buyer_id", "seller_id"}, "", ""},
+			{"users", []string{"id", "name"}, "buyers", ""},
+			{"users", []string{"id"}, "sellers", ""},
 		},
 	},
 	{
 		tablesPart:  "routes:",
 		expectedErr: true,
 	},
+	{
+		tablesPart:  "users:id;users:name",
+		expectedErr: true,
+	},
+	{
+		tablesPart:  "users@:id",
+		expectedErr: true,
+	},
+	{
+		tablesPart:  "@buyers:id",
+		expectedErr: true,
+	},
+	{
+		tablesPart:  "users@buyers@sellers:id",
+		expectedErr: true,
+	},
 	{
 		tablesPart:  "",
 		expectedErr: true,
 	},
+	{
+		tablesPart: "\"my.table\":\"col,1\",`col``2`;core.customers@c:id",
+		expected: []*QueryTable{
+			{"my.table", []string{"col,1", "col`2"}, "", ""},
+			{"customers", []string{"id"}, "c", "core"},
+		},
+	},
+	{
+		tablesPart:  "routes:id;\"\":id",
+		expectedErr: true,
+	},
+	{
+		tablesPart: "core.customers:id;customers:id",
+		expected: []*QueryTable{
+			{"customers", []string{"id"}, "", "core"},
+			{"customers", []string{"id"}, "", ""},
+		},
+	},
+	{
+		tablesPart:  "a.b.c:id",
+		expectedErr: true,
+	},
+}
+
+func TestParseTablesPartErrorPosition(t *testing.T) {
+	_, err := parseTablesPart("routes:id,name;stations:id,")
+	expected := "Expected name of column, but got end of input at column 28:\n" +
+		"  routes:id,name;stations:id,\n" +
+		"                             ^"
+	if err == nil || err.Error() != expected {
+		t.Errorf("EXP %s\nGOT %v", expected, err)
+	}
+
+	_, err = parseTablesPart("users@buyers@sellers:id")
+	expected = "Table definition should be in format 'table:column1,column2,...', but got '@' at column 13:\n" +
+		"  users@buyers@sellers:id\n" +
+		"              ^"
+	if err == nil || err.Error() != expected {
+		t.Errorf("EXP %s\nGOT %v", expected, err)
+	}
 }
 
 func TestParseTablesPart(t *testing.T) {
@@ -147,7 +211,22 @@ var testsRelations = []testRelationsTripl{
 		expectedErr:   true,
 	},
 	{
-		relationsPart: "routes.id=stations_for_routes.id.id",
+		relationsPart: "routes.id=stations_for_routes.id.id.id",
+		expectedErr:   true,
+	},
+	{
+		relationsPart: "core.customers.id=billing.invoices.customer_id;\"my.table\".`col``1`=customers.id",
+		expected: []*QueryRelation{
+			{"core.customers", "id", "billing.invoices", "customer_id"},
+			{"\"my.table\"", "col`1", "customers", "id"},
+		},
+	},
+	{
+		relationsPart: "routes.id=stations.\"id",
+		expectedErr:   true,
+	},
+	{
+		relationsPart: "routes.id=stations.id;",
 		expectedErr:   true,
 	},
 	{
@@ -198,9 +277,9 @@ var testsQuery = []testQueryInput{
 		relationsPart: "routes.id=stations_for_routes.route_id;stations.id=stations_for_routes.station_id",
 		expected: &Query{
 			tables: []*QueryTable{
-				{"routes", []string{"id", "name"}},
-				{"stations", []string{"id", "sname"}},
-				{"stations_for_routes", []string{"station_id", "route_id", "ord"}},
+				{"routes", []string{"id", "name"}, "", ""},
+				{"stations", []string{"id", "sname"}, "", ""},
+				{"stations_for_routes", []string{"station_id", "route_id", "ord"}, "", ""},
 			},
 			relations: []*QueryRelation{
 				{"routes", "id", "stations_for_routes", "route_id"},
@@ -0,0 +1,109 @@
+package dumper
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Restore executes SQL statements which are read from r, e.g. from file created by SqlWriter.
+// All statements are executed using one connection, so session settings like FOREIGN_KEY_CHECKS
+// are applied to the following statements. It returns number of executed statements.
+// Only WithLogger is used from options.
+func Restore(ctx context.Context, db *sql.DB, r io.Reader, opts ...Option) (statements int, err error) {
+	logger := newDumpOptions(opts).logger
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	reader := newStatementReader(r)
+	for {
+		statement, err := reader.next()
+		if err == io.EOF {
+			return statements, nil
+		}
+		if err != nil {
+			return statements, err
+		}
+		_, err = conn.ExecContext(ctx, statement)
+		if err != nil {
+			return statements, fmt.Errorf("Fail to execute statement #%d: %s", statements+1, err)
+		}
+		statements++
+		logger.Debug("Statement is executed", "number", statements)
+	}
+}
+
+// statementReader splits SQL into statements by semicolons which are not inside quotes or comments
+type statementReader struct {
+	r *bufio.Reader
+}
+
+func newStatementReader(r io.Reader) *statementReader {
+	return &statementReader{bufio.NewReader(r)}
+}
+
+// next returns the next non-empty statement without semicolon or io.EOF
+func (sr *statementReader) next() (string, error) {
+	var statement strings.Builder
+	var quote rune
+	escaped := false
+	lineComment := false
+	for {
+		ch, _, err := sr.r.ReadRune()
+		if err == io.EOF {
+			if quote != 0 {
+				return "", fmt.Errorf("Unexpected end of SQL: quote %c is not closed", quote)
+			}
+			if s := strings.TrimSpace(statement.String()); s != "" {
+				return s, nil
+			}
+			return "", io.EOF
+		}
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case lineComment:
+			if ch == '\n' {
+				lineComment = false
+				statement.WriteRune(ch)
+			}
+			continue
+		case escaped:
+			escaped = false
+		case quote != 0:
+			if ch == '\\' && quote != '`' {
+				escaped = true
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '#' || (ch == '-' && sr.peekLineComment()):
+			lineComment = true
+			continue
+		case ch == ';':
+			if s := strings.TrimSpace(statement.String()); s != "" {
+				return s, nil
+			}
+			statement.Reset()
+			continue
+		}
+		statement.WriteRune(ch)
+	}
+}
+
+// peekLineComment checks that current '-' starts comment "-- "
+func (sr *statementReader) peekLineComment() bool {
+	next, err := sr.r.Peek(2)
+	if err != nil || len(next) < 2 {
+		return false
+	}
+	return next[0] == '-' && (next[1] == ' ' || next[1] == '\t' || next[1] == '\n')
+}
@@ -0,0 +1,66 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStatementReader(t *testing.T) {
+	sql := "SET FOREIGN_KEY_CHECKS=0;\n" +
+		"-- comment with ; inside\n" +
+		"# another comment;\n" +
+		"INSERT INTO `t;1` (`a`) VALUES ('x;y', 'it\\'s', \"q;\", 'a''b', 5-3);\n" +
+		";;\n" +
+		"SELECT 1"
+	reader := newStatementReader(strings.NewReader(sql))
+	statements := []string{}
+	for {
+		statement, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		statements = append(statements, statement)
+	}
+	expected := []string{
+		"SET FOREIGN_KEY_CHECKS=0",
+		"INSERT INTO `t;1` (`a`) VALUES ('x;y', 'it\\'s', \"q;\", 'a''b', 5-3)",
+		"SELECT 1",
+	}
+	assert.Equal(t, expected, statements)
+}
+
+func TestStatementReaderUnclosedQuote(t *testing.T) {
+	reader := newStatementReader(strings.NewReader("INSERT INTO t VALUES ('abc);"))
+	_, err := reader.next()
+	assert.EqualError(t, err, "Unexpected end of SQL: quote ' is not closed")
+}
+
+func TestRestore(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectExec("SET FOREIGN_KEY_CHECKS=0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `routes` (.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO `routes` (.+)").WillReturnError(fmt.Errorf("Duplicate entry"))
+
+	sql := "SET FOREIGN_KEY_CHECKS=0;\n" +
+		"INSERT INTO `routes` (`id`) VALUES (1);\n" +
+		"INSERT INTO `routes` (`id`) VALUES (1);\n"
+	statements, err := Restore(context.Background(), mockDB, strings.NewReader(sql))
+	assert.EqualError(t, err, "Fail to execute statement #3: Duplicate entry")
+	assert.Equal(t, 2, statements)
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
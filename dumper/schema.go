@@ -0,0 +1,151 @@
+package dumper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// TableInfo contains name of table and number of rows estimated by DB
+type TableInfo struct {
+	Name string        `db:"name"`
+	Rows sql.NullInt64 `db:"rows"`
+}
+
+// ForeignKey represents column which references column of another table
+type ForeignKey struct {
+	Name             string `db:"name"`
+	Table            string `db:"table_name"`
+	Column           string `db:"column_name"`
+	ReferencedTable  string `db:"referenced_table_name"`
+	ReferencedColumn string `db:"referenced_column_name"`
+}
+
+// TableDescription contains columns of table, its foreign keys and foreign keys of other tables which reference it
+type TableDescription struct {
+	Name        string
+	Columns     []TableColumnDDL
+	ForeignKeys []*ForeignKey
+}
+
+// Relation returns foreign key in format of relations argument: table1.column1=table2.column2
+func (fk *ForeignKey) Relation() string {
+	return fk.ReferencedTable + "." + fk.ReferencedColumn + "=" + fk.Table + "." + fk.Column
+}
+
+// ListTables returns tables of current database
+func ListTables(ctx context.Context, db *sql.DB) ([]*TableInfo, error) {
+	tables := []*TableInfo{}
+	query := "SELECT `TABLE_NAME` AS `name`, `TABLE_ROWS` AS `rows` FROM `information_schema`.`TABLES`" +
+		" WHERE `TABLE_SCHEMA` = DATABASE() AND `TABLE_TYPE` = 'BASE TABLE' ORDER BY `TABLE_NAME`"
+	err := sqlx.NewDb(db, "mysql").SelectContext(ctx, &tables, query)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to list tables: %s", err)
+	}
+	return tables, nil
+}
+
+// ListRelations returns foreign keys of current database. If table is not empty,
+// only foreign keys of the table and foreign keys which reference the table are returned.
+func ListRelations(ctx context.Context, db *sql.DB, table string) ([]*ForeignKey, error) {
+	return listForeignKeys(ctx, sqlx.NewDb(db, "mysql"), table)
+}
+
+// DescribeTable returns columns, keys and foreign keys of table
+func DescribeTable(ctx context.Context, db *sql.DB, table string) (*TableDescription, error) {
+	sqlxDB := sqlx.NewDb(db, "mysql")
+	columns, err := getTableDescription(ctx, sqlxDB, table)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to describe table '%s': %s", table, err)
+	}
+	foreignKeys, err := listForeignKeys(ctx, sqlxDB, table)
+	if err != nil {
+		return nil, err
+	}
+	return &TableDescription{table, columns, foreignKeys}, nil
+}
+
+func listForeignKeys(ctx context.Context, db *sqlx.DB, table string) ([]*ForeignKey, error) {
+	foreignKeys := []*ForeignKey{}
+	query := "SELECT `CONSTRAINT_NAME` AS `name`, `TABLE_NAME` AS `table_name`, `COLUMN_NAME` AS `column_name`," +
+		" `REFERENCED_TABLE_NAME` AS `referenced_table_name`, `REFERENCED_COLUMN_NAME` AS `referenced_column_name`" +
+		" FROM `information_schema`.`KEY_COLUMN_USAGE`" +
+		" WHERE `TABLE_SCHEMA` = DATABASE() AND `REFERENCED_TABLE_NAME` IS NOT NULL"
+	args := []interface{}{}
+	if table != "" {
+		query += " AND (`TABLE_NAME` = ? OR `REFERENCED_TABLE_NAME` = ?)"
+		args = append(args, table, table)
+	}
+	query += " ORDER BY `TABLE_NAME`, `CONSTRAINT_NAME`, `ORDINAL_POSITION`"
+	err := db.SelectContext(ctx, &foreignKeys, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to list foreign keys: %s", err)
+	}
+	return foreignKeys, nil
+}
+
+// PrintTables prints tables with estimated rows as table
+func PrintTables(out io.Writer, tables []*TableInfo) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tEST. ROWS")
+	for _, table := range tables {
+		rows := "-"
+		if table.Rows.Valid {
+			rows = fmt.Sprintf("%d", table.Rows.Int64)
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", table.Name, rows)
+	}
+	tw.Flush()
+}
+
+// PrintRelations prints foreign keys in format of relations argument
+func PrintRelations(out io.Writer, foreignKeys []*ForeignKey) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RELATION\tCONSTRAINT")
+	for _, fk := range foreignKeys {
+		fmt.Fprintf(tw, "%s\t%s\n", fk.Relation(), fk.Name)
+	}
+	tw.Flush()
+}
+
+// PrintTableDescription prints columns with keys and foreign keys of table
+func PrintTableDescription(out io.Writer, description *TableDescription) {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLUMN\tTYPE\tNULL\tKEY\tDEFAULT\tEXTRA")
+	for _, column := range description.Columns {
+		defaultValue := "-"
+		if column.Default.Valid {
+			defaultValue = column.Default.String
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", column.Field, column.Type, column.Null, dashIfEmpty(column.Key), defaultValue, dashIfEmpty(column.Extra))
+	}
+	tw.Flush()
+
+	references := []string{}
+	referencedBy := []string{}
+	for _, fk := range description.ForeignKeys {
+		if fk.Table == description.Name {
+			references = append(references, fmt.Sprintf("  %s -> %s.%s (%s)", fk.Column, fk.ReferencedTable, fk.ReferencedColumn, fk.Name))
+		}
+		if fk.ReferencedTable == description.Name {
+			referencedBy = append(referencedBy, fmt.Sprintf("  %s <- %s.%s (%s)", fk.ReferencedColumn, fk.Table, fk.Column, fk.Name))
+		}
+	}
+	if len(references) > 0 {
+		fmt.Fprintf(out, "\nForeign keys:\n%s\n", strings.Join(references, "\n"))
+	}
+	if len(referencedBy) > 0 {
+		fmt.Fprintf(out, "\nReferenced by:\n%s\n", strings.Join(referencedBy, "\n"))
+	}
+}
+
+func dashIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,128 @@
+package dumper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"testing"
+)
+
+var foreignKeyColumns = []string{"name", "table_name", "column_name", "referenced_table_name", "referenced_column_name"}
+
+func TestListTables(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`TABLES` WHERE (.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "rows"}).AddRow("routes", int64(3)).AddRow("stations", nil))
+
+	tables, err := ListTables(context.Background(), mockDB)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	out := &bytes.Buffer{}
+	PrintTables(out, tables)
+	expected := "TABLE     EST. ROWS\n" +
+		"routes    3\n" +
+		"stations  -\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestListTablesError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectQuery("SELECT (.+)").WillReturnError(fmt.Errorf("Some DB error"))
+	_, err = ListTables(context.Background(), mockDB)
+	assert.EqualError(t, err, "Fail to list tables: Some DB error")
+}
+
+func TestListRelations(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`KEY_COLUMN_USAGE` WHERE (.+) ORDER BY (.+)").
+		WithArgs().
+		WillReturnRows(sqlmock.NewRows(foreignKeyColumns).
+			AddRow("fk_route", "stations_for_routes", "route_id", "routes", "id").
+			AddRow("fk_station", "stations_for_routes", "station_id", "stations", "id"))
+
+	foreignKeys, err := ListRelations(context.Background(), mockDB, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	out := &bytes.Buffer{}
+	PrintRelations(out, foreignKeys)
+	expected := "RELATION                                    CONSTRAINT\n" +
+		"routes.id=stations_for_routes.route_id      fk_route\n" +
+		"stations.id=stations_for_routes.station_id  fk_station\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestDescribeTable(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectQuery("DESCRIBE `stations_for_routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).
+				AddRow("id", "bigint(20)", "NO", "PRI", nil, "auto_increment").
+				AddRow("route_id", "bigint(20)", "NO", "MUL", nil, "").
+				AddRow("ord", "int(11)", "YES", "", "0", ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`KEY_COLUMN_USAGE` WHERE (.+) AND \\(`TABLE_NAME` = \\? OR `REFERENCED_TABLE_NAME` = \\?\\)").
+		WithArgs("stations_for_routes", "stations_for_routes").
+		WillReturnRows(sqlmock.NewRows(foreignKeyColumns).
+			AddRow("fk_route", "stations_for_routes", "route_id", "routes", "id").
+			AddRow("fk_parent", "stations_for_routes_log", "parent_id", "stations_for_routes", "id"))
+
+	description, err := DescribeTable(context.Background(), mockDB, "stations_for_routes")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	out := &bytes.Buffer{}
+	PrintTableDescription(out, description)
+	expected := "COLUMN    TYPE        NULL  KEY  DEFAULT  EXTRA\n" +
+		"id        bigint(20)  NO    PRI  -        auto_increment\n" +
+		"route_id  bigint(20)  NO    MUL  -        -\n" +
+		"ord       int(11)     YES   -    0        -\n" +
+		"\n" +
+		"Foreign keys:\n" +
+		"  route_id -> routes.id (fk_route)\n" +
+		"\n" +
+		"Referenced by:\n" +
+		"  id <- stations_for_routes_log.parent_id (fk_parent)\n"
+	assert.Equal(t, expected, out.String())
+}
+
+func TestDescribeTableError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectQuery("DESCRIBE `unknown`").WillReturnError(fmt.Errorf("Table doesn't exist"))
+	_, err = DescribeTable(context.Background(), mockDB, "unknown")
+	assert.EqualError(t, err, "Fail to describe table 'unknown': Table doesn't exist")
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnRows(sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""))
+	mock.ExpectQuery("SELECT (.+)").WillReturnError(fmt.Errorf("Some DB error"))
+	_, err = DescribeTable(context.Background(), mockDB, "routes")
+	assert.EqualError(t, err, "Fail to list foreign keys: Some DB error")
+}
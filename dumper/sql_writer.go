@@ -1,10 +1,23 @@
-package main
+package dumper
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
 
+func init() {
+	RegisterFormat(&Format{
+		Name:      "sql",
+		Extension: "sql",
+		DDL:       true,
+		Separated: true,
+		NewWriter: func(fw FileWriter, dstFile string, dstDir string, _ map[string]string) (DataWriter, error) {
+			return NewSqlWriter(fw, dstFile, dstDir), nil
+		},
+	})
+}
+
 // SqlWriter writes data in sql format using FileWriter
 type SqlWriter struct {
 	fw      FileWriter
@@ -22,11 +35,11 @@ func NewSqlWriter(fw FileWriter, dstFile, dstDir string) *SqlWriter {
 }
 
 // WriteDDL writes SQL-query which creates tables - DDL
-func (w *SqlWriter) WriteDDL(tableName string, ddl string) (err error) {
+func (w *SqlWriter) WriteDDL(ctx context.Context, tableName string, ddl string) (err error) {
 	contents := "SET FOREIGN_KEY_CHECKS=0;\n"
 	contents += ddl + "\n"
 	contents += "SET FOREIGN_KEY_CHECKS=1;\n"
-	f, err := w.fw.getFileHandler(w.getFilename(tableName))
+	f, err := w.fw.GetFileHandler(w.Filename(tableName))
 	if err != nil {
 		return err
 	}
@@ -35,21 +48,25 @@ func (w *SqlWriter) WriteDDL(tableName string, ddl string) (err error) {
 	if err != nil {
 		return fmt.Errorf("Error at writing DDL to file: %s", err)
 	}
+	loggerFromContext(ctx).Debug("DDL is written into file", "table", tableName, "file", w.Filename(tableName))
 	return
 }
 
 // WriteRows writes result rows in sql-insert format
-func (w *SqlWriter) WriteRows(tableName string, columns []string, rows []*map[string]interface{}) (err error) {
-	f, err := w.fw.getFileHandler(w.getFilename(tableName))
+func (w *SqlWriter) WriteRows(ctx context.Context, tableName string, columns []string, rows []*map[string]interface{}) (err error) {
+	f, err := w.fw.GetFileHandler(w.Filename(tableName))
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 	columnsNames := make([]string, 0)
 	for _, column := range columns {
-		columnsNames = append(columnsNames, "`"+column+"`")
+		columnsNames = append(columnsNames, sqlColumn(column))
 	}
 	for _, row := range rows {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		values := make([]string, 0)
 		for _, field := range columns {
 			v := (*row)[field]
@@ -76,21 +93,28 @@ func (w *SqlWriter) WriteRows(tableName string, columns []string, rows []*map[st
 			}
 			values = append(values, value)
 		}
-		insert := "INSERT INTO `" + tableName + "` (" + strings.Join(columnsNames, ", ") + ") " +
+		insert := "INSERT INTO " + sqlName(tableName) + " (" + strings.Join(columnsNames, ", ") + ") " +
 			"VALUES (" + strings.Join(values, ", ") + ");\n"
 		_, err = f.WriteString(insert)
 		if err != nil {
 			return fmt.Errorf("Error at writing rows to file: %s", err)
 		}
 	}
+	loggerFromContext(ctx).Debug("Rows are written into file", "table", tableName, "file", w.Filename(tableName), "rows", len(rows))
 	return
 }
 
-func (w *SqlWriter) getFilename(tableName string) (filename string) {
+// FileWriter returns file writer which writes files of writer
+func (w *SqlWriter) FileWriter() FileWriter {
+	return w.fw
+}
+
+// Filename returns name of file for rows and DDL of table
+func (w *SqlWriter) Filename(tableName string) (filename string) {
 	if w.dstDir != "" {
-		return w.dstDir + "/" + tableName + ".sql"
+		return withCompressionExtension(w.fw, tableFilename(w.dstDir, tableName, "sql"))
 	}
-	return w.dstFile
+	return withCompressionExtension(w.fw, w.dstFile)
 }
 
 func escapeString(str string) string {
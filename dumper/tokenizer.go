@@ -0,0 +1,123 @@
+package dumper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// separators of request syntax. They can be used in names only inside quotes.
+const requestSeparators = ";:,.=@"
+
+// token is identifier or separator of request syntax
+type token struct {
+	separator rune
+	value     string
+	quoted    bool
+	column    int
+}
+
+func (t *token) isSeparator(separator rune) bool {
+	return t != nil && t.separator == separator
+}
+
+func (t *token) String() string {
+	if t == nil {
+		return "end of input"
+	}
+	if t.separator != 0 {
+		return fmt.Sprintf("'%c'", t.separator)
+	}
+	return fmt.Sprintf("'%s'", formatIdentifier(t.value))
+}
+
+// tokenize splits part of request into identifiers and separators.
+// Identifiers with separators can be quoted with double quotes or backticks,
+// quote inside of identifier is escaped by doubling, e.g. "my""table".
+func tokenize(part string) (tokens []*token, err error) {
+	tokens = make([]*token, 0)
+	runes := []rune(part)
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+		switch {
+		case strings.ContainsRune(requestSeparators, ch):
+			tokens = append(tokens, &token{separator: ch, column: i + 1})
+			i++
+		case ch == '"' || ch == '`':
+			start := i
+			var value strings.Builder
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] != ch {
+					value.WriteRune(runes[i])
+					continue
+				}
+				if i+1 < len(runes) && runes[i+1] == ch {
+					value.WriteRune(ch)
+					i++
+					continue
+				}
+				closed = true
+				i++
+				break
+			}
+			if !closed {
+				return nil, positionError(part, start+1, "Quote %c is not closed", ch)
+			}
+			tokens = append(tokens, &token{value: value.String(), quoted: true, column: start + 1})
+		default:
+			start := i
+			for i < len(runes) && !strings.ContainsRune(requestSeparators+"\"`", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, &token{value: string(runes[start:i]), column: start + 1})
+		}
+	}
+	return tokens, nil
+}
+
+// formatIdentifier returns name as it should be written in request: quoted if it contains separators or quotes
+func formatIdentifier(name string) string {
+	if name != "" && !strings.ContainsAny(name, requestSeparators+"\"`") {
+		return name
+	}
+	return "\"" + strings.Replace(name, "\"", "\"\"", -1) + "\""
+}
+
+// qualifiedName returns name of table with schema in format of request: schema.table or table
+func qualifiedName(schema string, name string) string {
+	if schema == "" {
+		return formatIdentifier(name)
+	}
+	return formatIdentifier(schema) + "." + formatIdentifier(name)
+}
+
+// splitQualifiedName returns parts of name in format of request, e.g. schema and table from schema.table
+func splitQualifiedName(name string) (parts []string, err error) {
+	tokens, err := tokenize(name)
+	if err != nil {
+		return nil, err
+	}
+	parts = make([]string, 0)
+	for i, t := range tokens {
+		if i%2 == 1 {
+			if !t.isSeparator('.') {
+				return nil, positionError(name, t.column, "Expected '.', but got %s", t)
+			}
+			continue
+		}
+		if t.separator != 0 {
+			return nil, positionError(name, t.column, "Expected name, but got %s", t)
+		}
+		parts = append(parts, t.value)
+	}
+	if len(tokens)%2 == 0 {
+		return nil, positionError(name, len([]rune(name))+1, "Expected name, but got end of input")
+	}
+	return parts, nil
+}
+
+// positionError returns error with part of request and pointer to column of problem
+func positionError(part string, column int, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	return fmt.Errorf("%s at column %d:\n  %s\n  %s^", message, column, part, strings.Repeat(" ", column-1))
+}
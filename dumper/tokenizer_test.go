@@ -0,0 +1,45 @@
+package dumper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize("db.\"my.table\"@`a``b`:id")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []*token{
+		{0, "db", false, 1},
+		{'.', "", false, 3},
+		{0, "my.table", true, 4},
+		{'@', "", false, 14},
+		{0, "a`b", true, 15},
+		{':', "", false, 21},
+		{0, "id", false, 22},
+	}
+	assert.Equal(t, expected, tokens)
+
+	_, err = tokenize("routes:`id")
+	assert.EqualError(t, err, "Quote ` is not closed at column 8:\n  routes:`id\n         ^")
+}
+
+func TestFormatIdentifier(t *testing.T) {
+	assert.Equal(t, "routes", formatIdentifier("routes"))
+	assert.Equal(t, "\"my.table\"", formatIdentifier("my.table"))
+	assert.Equal(t, "\"a\"\"b\"", formatIdentifier("a\"b"))
+	assert.Equal(t, "\"\"", formatIdentifier(""))
+	assert.Equal(t, "core.\"my.table\"", qualifiedName("core", "my.table"))
+}
+
+func TestSplitQualifiedName(t *testing.T) {
+	parts, err := splitQualifiedName("core.\"my.table\"")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"core", "my.table"}, parts)
+
+	for _, name := range []string{"", "core.", ".table", "core:table"} {
+		_, err = splitQualifiedName(name)
+		assert.NotNil(t, err, name)
+	}
+}
@@ -0,0 +1,19 @@
+package dumper
+
+func contains(haystack []string, needle string) bool {
+	for _, a := range haystack {
+		if a == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(haystack []string, needles []string) bool {
+	for _, needle := range needles {
+		if !contains(haystack, needle) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,4 +1,4 @@
-package main
+package dumper
 
 import (
 	"testing"
@@ -29,3 +29,15 @@ func TestContains(t *testing.T) {
 		}
 	}
 }
+
+func TestContainsAll(t *testing.T) {
+	if !containsAll([]string{"abc", "def"}, []string{"def", "abc"}) {
+		t.Errorf("Expected true")
+	}
+	if containsAll([]string{"abc", "def"}, []string{"def", "ghi"}) {
+		t.Errorf("Expected false")
+	}
+	if !containsAll([]string{"abc"}, []string{}) {
+		t.Errorf("Expected true for empty needles")
+	}
+}
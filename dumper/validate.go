@@ -0,0 +1,234 @@
+package dumper
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"sort"
+	"strings"
+)
+
+// schemaColumn represents column of table from information schema
+type schemaColumn struct {
+	Schema string `db:"table_schema"`
+	// CurrentSchema is name of current database for its columns and empty for other databases
+	CurrentSchema string `db:"current_schema"`
+	Table         string `db:"table_name"`
+	Column        string `db:"column_name"`
+	Type          string `db:"column_type"`
+}
+
+// schemaTables contains types of columns mapped by full names of tables and columns
+type schemaTables map[string]map[string]string
+
+// Validate checks tables, columns and relations of query against schema of current database.
+// All found problems are returned as one error.
+func Validate(ctx context.Context, db *sql.DB, query *Query) error {
+	schema, err := readSchema(ctx, sqlx.NewDb(db, "mysql"), query.schemas())
+	if err != nil {
+		return err
+	}
+	problems := query.validateSchema(schema)
+	if len(problems) > 0 {
+		return fmt.Errorf("Query does not match schema:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// readSchema reads columns of current database and of other databases which are used by query.
+// Tables of other databases are mapped by names qualified by schema.
+// Tables of current database are mapped by both plain and qualified names, e.g. customers and core.customers.
+func readSchema(ctx context.Context, db *sqlx.DB, schemas []string) (schemaTables, error) {
+	columns := []*schemaColumn{}
+	query := "SELECT IF(`TABLE_SCHEMA` = DATABASE(), '', `TABLE_SCHEMA`) AS `table_schema`," +
+		" IF(`TABLE_SCHEMA` = DATABASE(), `TABLE_SCHEMA`, '') AS `current_schema`, `TABLE_NAME` AS `table_name`," +
+		" `COLUMN_NAME` AS `column_name`, `COLUMN_TYPE` AS `column_type`" +
+		" FROM `information_schema`.`COLUMNS` WHERE `TABLE_SCHEMA` = DATABASE()"
+	args := []interface{}{}
+	if len(schemas) > 0 {
+		query += " OR `TABLE_SCHEMA` IN (?" + strings.Repeat(", ?", len(schemas)-1) + ")"
+		for _, schema := range schemas {
+			args = append(args, schema)
+		}
+	}
+	query += " ORDER BY `TABLE_SCHEMA`, `TABLE_NAME`, `ORDINAL_POSITION`"
+	err := db.SelectContext(ctx, &columns, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to read schema: %s", err)
+	}
+	schema := make(schemaTables)
+	for _, column := range columns {
+		schema.addColumn(qualifiedName(column.Schema, column.Table), column)
+		if column.CurrentSchema != "" {
+			schema.addColumn(qualifiedName(column.CurrentSchema, column.Table), column)
+		}
+	}
+	return schema, nil
+}
+
+// validateSchema returns descriptions of all problems of query
+func (q *Query) validateSchema(schema schemaTables) (problems []string) {
+	problems = make([]string, 0)
+	for _, qt := range q.tables {
+		name := qt.fullName()
+		columns, ok := schema[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("Table '%s' does not exist.%s", name, didYouMean(name, schema.tableNames())))
+			continue
+		}
+		for _, column := range qt.columns {
+			if _, ok := columns[column]; !ok {
+				problems = append(problems, fmt.Sprintf("Column '%s.%s' does not exist.%s", name, column, didYouMean(column, schema.columnNames(name))))
+			}
+		}
+	}
+
+	refs := make([]string, 0)
+	for _, qt := range q.tables {
+		refs = append(refs, qt.ref())
+	}
+	for _, qr := range q.relations {
+		relation := qr.table1 + "." + qr.column1 + "=" + qr.table2 + "." + qr.column2
+		type1, problem1 := q.relationColumnType(schema, relation, qr.table1, qr.column1, refs)
+		type2, problem2 := q.relationColumnType(schema, relation, qr.table2, qr.column2, refs)
+		for _, problem := range []string{problem1, problem2} {
+			if problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+		if type1 != "" && type2 != "" && columnTypeFamily(type1) != columnTypeFamily(type2) {
+			problems = append(problems, fmt.Sprintf("Relation '%s' compares columns of incompatible types %s and %s", relation, type1, type2))
+		}
+	}
+
+	for i, qt := range q.tables {
+		if i > 0 && !q.hasRelation(qt.ref()) {
+			problems = append(problems, fmt.Sprintf("Table '%s' has no relations with other tables", qt.ref()))
+		}
+	}
+	return problems
+}
+
+// relationColumnType returns type of column of relation or description of problem
+func (q *Query) relationColumnType(schema schemaTables, relation string, ref string, column string, refs []string) (columnType string, problem string) {
+	qt := q.findTable(ref)
+	if qt == nil {
+		return "", fmt.Sprintf("Relation '%s' uses table '%s' which is not in tables argument.%s", relation, ref, didYouMean(ref, refs))
+	}
+	name := qt.fullName()
+	columns, ok := schema[name]
+	if !ok {
+		return "", ""
+	}
+	columnType, ok = columns[column]
+	if !ok {
+		return "", fmt.Sprintf("Relation '%s' uses column '%s.%s' which does not exist.%s", relation, name, column, didYouMean(column, schema.columnNames(name)))
+	}
+	return columnType, ""
+}
+
+// schemas returns names of databases which qualify tables of query
+func (q *Query) schemas() []string {
+	schemas := make([]string, 0)
+	for _, qt := range q.tables {
+		if qt.schema != "" && !contains(schemas, qt.schema) {
+			schemas = append(schemas, qt.schema)
+		}
+	}
+	return schemas
+}
+
+func (q *Query) hasRelation(ref string) bool {
+	for _, qr := range q.relations {
+		if qr.table1 == ref || qr.table2 == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func (schema schemaTables) addColumn(table string, column *schemaColumn) {
+	if schema[table] == nil {
+		schema[table] = make(map[string]string)
+	}
+	schema[table][column.Column] = column.Type
+}
+
+func (schema schemaTables) tableNames() []string {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (schema schemaTables) columnNames(table string) []string {
+	names := make([]string, 0, len(schema[table]))
+	for name := range schema[table] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// columnTypeFamily groups column types which can be compared without conversion
+func columnTypeFamily(columnType string) string {
+	baseType := strings.ToLower(columnType)
+	if i := strings.IndexAny(baseType, "( "); i >= 0 {
+		baseType = baseType[:i]
+	}
+	switch baseType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "bit", "bool", "boolean", "year":
+		return "integer"
+	case "decimal", "numeric", "float", "double", "real":
+		return "number"
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set":
+		return "string"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return "binary"
+	case "date", "datetime", "timestamp":
+		return "date"
+	}
+	return baseType
+}
+
+// didYouMean returns suggestion of the closest candidate or empty string if there is no similar candidate
+func didYouMean(name string, candidates []string) string {
+	best := ""
+	bestDistance := len(name)/3 + 2
+	for _, candidate := range candidates {
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" Did you mean '%s'?", best)
+}
+
+func levenshtein(a string, b string) int {
+	s1 := []rune(a)
+	s2 := []rune(b)
+	previous := make([]int, len(s2)+1)
+	current := make([]int, len(s2)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s1); i++ {
+		current[0] = i
+		for j := 1; j <= len(s2); j++ {
+			cost := 1
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(s2)]
+}
@@ -0,0 +1,146 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"testing"
+)
+
+var validationSchema = schemaTables{
+	"routes":              {"id": "int(10) unsigned", "name": "varchar(255)"},
+	"stations":            {"id": "bigint(20)", "code": "char(3)"},
+	"stations_for_routes": {"route_id": "int(11)", "station_id": "bigint(20)", "station_code": "varchar(16)"},
+}
+
+func TestValidateSchema(t *testing.T) {
+	query := &Query{
+		tables: []*QueryTable{
+			{"routes", []string{"id", "name"}, "", ""},
+			{"stations_for_routes", []string{"route_id", "station_id"}, "", ""},
+			{"stations", []string{"id"}, "start", ""},
+		},
+		relations: []*QueryRelation{
+			{"routes", "id", "stations_for_routes", "route_id"},
+			{"start", "id", "stations_for_routes", "station_id"},
+		},
+		primaryInterval: []int64{1, 10},
+	}
+	assert.Equal(t, []string{}, query.validateSchema(validationSchema))
+}
+
+func TestValidateSchemaProblems(t *testing.T) {
+	query := &Query{
+		tables: []*QueryTable{
+			{"route", []string{"id"}, "", ""},
+			{"stations_for_routes", []string{"route_id", "staton_id"}, "", ""},
+			{"stations", []string{"id", "code"}, "", ""},
+			{"logs", []string{"id"}, "", ""},
+		},
+		relations: []*QueryRelation{
+			{"routes", "id", "stations_for_routes", "route_id"},
+			{"stations", "code", "stations_for_routes", "station_id"},
+			{"stations", "id", "stations_for_routes", "stationid"},
+		},
+		primaryInterval: []int64{1, 10},
+	}
+	expected := []string{
+		"Table 'route' does not exist. Did you mean 'routes'?",
+		"Column 'stations_for_routes.staton_id' does not exist. Did you mean 'station_id'?",
+		"Table 'logs' does not exist.",
+		"Relation 'routes.id=stations_for_routes.route_id' uses table 'routes' which is not in tables argument. Did you mean 'route'?",
+		"Relation 'stations.code=stations_for_routes.station_id' compares columns of incompatible types char(3) and bigint(20)",
+		"Relation 'stations.id=stations_for_routes.stationid' uses column 'stations_for_routes.stationid' which does not exist. Did you mean 'station_id'?",
+		"Table 'logs' has no relations with other tables",
+	}
+	assert.Equal(t, expected, query.validateSchema(validationSchema))
+}
+
+func TestValidate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := &Query{
+		tables: []*QueryTable{
+			{"routes", []string{"id", "nmae"}, "", ""},
+		},
+		relations:       []*QueryRelation{},
+		primaryInterval: []int64{1, 10},
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`COLUMNS` (.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name", "column_type"}).
+			AddRow("routes", "id", "int(11)").
+			AddRow("routes", "name", "varchar(255)"))
+	err = Validate(context.Background(), db, query)
+	assert.EqualError(t, err, "Query does not match schema:\n  Column 'routes.nmae' does not exist. Did you mean 'name'?")
+
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`COLUMNS` (.+)").
+		WillReturnError(fmt.Errorf("Some DB error"))
+	err = Validate(context.Background(), db, query)
+	assert.EqualError(t, err, "Fail to read schema: Some DB error")
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestValidateTableQualifiedByCurrentDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := &Query{
+		tables: []*QueryTable{
+			{"customers", []string{"id", "name"}, "", "core"},
+			{"invoices", []string{"id", "customer_id"}, "", "billing"},
+		},
+		relations: []*QueryRelation{
+			{"core.customers", "id", "billing.invoices", "customer_id"},
+		},
+		primaryInterval: []int64{1, 10},
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`COLUMNS` (.+)").
+		WithArgs("core", "billing").
+		WillReturnRows(sqlmock.NewRows([]string{"table_schema", "current_schema", "table_name", "column_name", "column_type"}).
+			AddRow("billing", "", "invoices", "id", "int(11)").
+			AddRow("billing", "", "invoices", "customer_id", "int(11)").
+			AddRow("", "core", "customers", "id", "int(11)").
+			AddRow("", "core", "customers", "name", "varchar(255)"))
+	err = Validate(context.Background(), db, query)
+	assert.Nil(t, err)
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestColumnTypeFamily(t *testing.T) {
+	assert.Equal(t, columnTypeFamily("int(10) unsigned"), columnTypeFamily("BIGINT(20)"))
+	assert.Equal(t, columnTypeFamily("varchar(255)"), columnTypeFamily("enum('a','b')"))
+	assert.Equal(t, columnTypeFamily("datetime"), columnTypeFamily("timestamp"))
+	assert.NotEqual(t, columnTypeFamily("int(11)"), columnTypeFamily("char(11)"))
+	assert.Equal(t, "json", columnTypeFamily("json"))
+}
+
+func TestDidYouMean(t *testing.T) {
+	candidates := []string{"routes", "stations", "stations_for_routes"}
+	assert.Equal(t, " Did you mean 'routes'?", didYouMean("Route", candidates))
+	assert.Equal(t, " Did you mean 'stations'?", didYouMean("statoins", candidates))
+	assert.Equal(t, "", didYouMean("logs", candidates))
+	assert.Equal(t, "", didYouMean("routes", []string{}))
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("routes", "routes"))
+	assert.Equal(t, 3, levenshtein("kitten", "sitting"))
+	assert.Equal(t, 6, levenshtein("", "routes"))
+	assert.Equal(t, 1, levenshtein("станция", "станции"))
+}
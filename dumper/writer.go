@@ -0,0 +1,102 @@
+package dumper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DataWriter is interface which can write result somewhere
+type DataWriter interface {
+	WriteRows(ctx context.Context, tableName string, columns []string, results []*map[string]interface{}) (err error)
+	WriteDDL(ctx context.Context, tableName string, ddl string) (err error)
+}
+
+// FileNamer is implemented by writers which write into files
+type FileNamer interface {
+	Filename(tableName string) (filename string)
+}
+
+// tableFilename returns name of file of table inside dir. It is built from raw names of schema and table
+// joined by dot. Dots, path separators and percent signs of names are escaped, so file is always inside dir.
+func tableFilename(dir string, tableName string, extension string) string {
+	parts, err := splitQualifiedName(tableName)
+	if err != nil {
+		parts = []string{tableName}
+	}
+	for i, part := range parts {
+		parts[i] = escapeFilenamePart(part)
+	}
+	return dir + "/" + strings.Join(parts, ".") + "." + extension
+}
+
+// escapeFilenamePart replaces characters which can not be used inside one name of file with %XX
+func escapeFilenamePart(part string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(part); i++ {
+		if strings.IndexByte("%./\\\x00", part[i]) >= 0 {
+			fmt.Fprintf(&escaped, "%%%02X", part[i])
+			continue
+		}
+		escaped.WriteByte(part[i])
+	}
+	return escaped.String()
+}
+
+func init() {
+	RegisterFormat(&Format{
+		Name:     "simple",
+		DDL:      true,
+		Combined: true,
+		NewWriter: func(_ FileWriter, _ string, _ string, _ map[string]string) (DataWriter, error) {
+			return &SimpleWriter{}, nil
+		},
+	})
+}
+
+// SimpleWriter writes result into stdout using simple format (concatenated values)
+type SimpleWriter struct {
+}
+
+// WriteRows prints result rows in simple format (concatenated values) into stdout
+func (w *SimpleWriter) WriteRows(ctx context.Context, tableName string, _ []string, rows []*map[string]interface{}) (err error) {
+	fmt.Println(tableName)
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		for field, v := range *row {
+			value := ""
+			switch typedValue := v.(type) {
+			case int:
+				value = fmt.Sprintf("%d", typedValue)
+				break
+			case int64:
+				value = fmt.Sprintf("%d", typedValue)
+				break
+			case float64:
+				value = fmt.Sprintf("%f", typedValue)
+				break
+			case string:
+				value = typedValue
+				break
+			case []uint8:
+				value = fmt.Sprintf("%s", typedValue)
+			case nil:
+				value = "NULL"
+			default:
+				value = "UNDEFINED"
+			}
+			fmt.Printf("%s = %s;||", field, value)
+		}
+		fmt.Println("")
+	}
+	loggerFromContext(ctx).Debug("Rows are printed", "table", tableName, "rows", len(rows))
+	return nil
+}
+
+// WriteDDL prints DDL as is into stdout
+func (w *SimpleWriter) WriteDDL(ctx context.Context, tableName string, ddl string) (err error) {
+	fmt.Println(ddl)
+	return nil
+}
@@ -0,0 +1,20 @@
+package dumper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTableFilename(t *testing.T) {
+	assert.Equal(t, "dumps/routes.sql", tableFilename("dumps", "routes", "sql"))
+	assert.Equal(t, "dumps/core.customers.sql", tableFilename("dumps", "core.customers", "sql"))
+	assert.Equal(t, "dumps/my%2Etable.sql", tableFilename("dumps", "\"my.table\"", "sql"))
+	assert.Equal(t, "dumps/%2E%2E%2F%2E%2E%2Fetc%2Fpasswd.csv", tableFilename("dumps", "\"../../etc/passwd\"", "csv"))
+	assert.Equal(t, "dumps/a%5Cb%25c.csv", tableFilename("dumps", "`a\\b%c`", "csv"))
+	assert.Equal(t, "dumps/my\"db.t%2F1.sql", tableFilename("dumps", "\"my\"\"db\".\"t/1\"", "sql"))
+
+	sqlWriter := NewSqlWriter(NewMemoryFileWriter(), "", "dumps")
+	assert.Equal(t, "dumps/my%2Etable.sql", sqlWriter.Filename("\"my.table\""))
+	csvWriter := NewCsvWriter(NewMemoryFileWriter(), "", "dumps", ",")
+	assert.Equal(t, "dumps/core.t%2F1.csv", csvWriter.Filename("core.\"t/1\""))
+}
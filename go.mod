@@ -0,0 +1,22 @@
+module github.com/rnixik/sql-dumper
+
+go 1.22
+
+require (
+	github.com/go-sql-driver/mysql v1.9.3
+	github.com/jmoiron/sqlx v1.3.5
+	github.com/klauspost/compress v1.18.0
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/crypto v0.31.0
+	golang.org/x/term v0.27.0
+	gopkg.in/DATA-DOG/go-sqlmock.v1 v1.3.0
+	gopkg.in/ini.v1 v1.67.0
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rnixik/sql-dumper/dumper"
+	"os"
+	"strings"
+)
+
+var commandsHelp = map[string]func() string{
+	"dump":      dumpHelp,
+	"plan":      planHelp,
+	"estimate":  estimateHelp,
+	"graph":     graphHelp,
+	"describe":  describeHelp,
+	"tables":    tablesHelp,
+	"relations": relationsHelp,
+	"restore":   restoreHelp,
+	"version":   versionHelp,
+}
+
+func showHelp() {
+	usage := "Dumps data from DB.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper <command> [OPTIONS] [arguments]\n"
+	usage += "       sql-dumper [OPTIONS] <tables> <interval> [relations]\n"
+	usage += "\n"
+	usage += "Commands:\n"
+	usage += "  dump       Dump rows of related tables into files or stdout. It is the default command\n"
+	usage += "  plan       Print DDL, queries with values and names of output files of dump without writing result\n"
+	usage += "  estimate   Run EXPLAIN for queries of dump and check estimated rows and full scans\n"
+	usage += "  graph      Draw tables and relations of dump as Graphviz DOT or Mermaid ER diagram\n"
+	usage += "  describe   Show columns, keys and foreign keys of table\n"
+	usage += "  tables     List tables of database with estimated rows\n"
+	usage += "  relations  List foreign keys in format of relations argument\n"
+	usage += "  restore    Execute SQL files created by dump in database\n"
+	usage += "  version    Print version\n"
+	usage += "\n"
+	usage += "Use \"sql-dumper help <command>\" or \"sql-dumper <command> --help\" to see options and arguments of command.\n"
+	fmt.Fprintln(os.Stderr, usage)
+}
+
+// showCommandHelp prints help of command or general help if command is unknown
+func showCommandHelp(name string) {
+	help, ok := commandsHelp[name]
+	if !ok {
+		showHelp()
+		return
+	}
+	fmt.Fprintln(os.Stderr, help())
+}
+
+func dumpHelp() string {
+	usage := "Dumps rows of related tables.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper dump [OPTIONS] <tables> <interval> [relations]\n"
+	usage += "\n"
+	usage += "Options:\n"
+	usage += "  --config <filename>        File with settings of connection to DB.\n"
+	usage += "                             It will be used if environment variables DB_NAME and DB_DSN are not defined (default .env)\n"
+	usage += connectionOptionsHelp()
+	usage += formatOptionsHelp()
+	usage += "  --file <filename>          Specify file to save combined result from all tables, - means stdout. Can't be used with --dir (default result.sql)\n"
+	usage += "  --dir <directory>          Specify directory to save the result in a separate file for every table\n"
+	usage += "  --compress {gzip|zstd}     Compress output files and add extension .gz or .zst to their names.\n"
+	usage += "                             By default it is detected by extension of --file, e.g. result.sql.gz\n"
+	usage += "  --overwrite                Replace existing output files instead of failing when they exist\n"
+	usage += "  --append                   Append to existing output files instead of failing when they exist\n"
+	usage += "  --dedup-spill-limit <num>  Number of primary keys per table to keep in memory for skipping duplicated rows.\n"
+	usage += "                             Keys are moved to disk after reaching the limit. 0 means no limit (default 0)\n"
+	usage += "  --dedup-spill-dir <dir>    Directory for primary keys moved to disk (default is system temporary directory)\n"
+	usage += "  --mask <rules>             Rules of masking values of columns: table1.column1=method;table2.column2=fake:kind\n"
+	usage += "                             Methods: null, hash, redact, fake, pseudo. Kinds of fake: email, name, first_name, last_name, phone\n"
+	usage += "  --mask-file <filename>     File with masking rules, one rule per line\n"
+	usage += "  --mask-secret <secret>     Secret for pseudo method. It can be set with environment variable MASK_SECRET\n"
+	usage += "  --dry-run                  Print DDL, queries with values and names of output files without writing result\n"
+	usage += "  --jobs <num>               Number of queries for separated tables to run concurrently (default 1)\n"
+	usage += "  --timeout <duration>       Time limit for the whole dump, e.g. 30m. 0 means no limit (default 0)\n"
+	usage += "  --query-timeout <duration> Time limit for every query, e.g. 90s. 0 means no limit (default 0)\n"
+	usage += "  --keep-partial             Keep output of failed or interrupted dump with suffix .partial instead of removing it\n"
+	usage += "  --chunk-size <num>         Number of values of the first column to select in one chunk. 0 means one chunk (default 0)\n"
+	usage += "  --checkpoint <filename>    Save progress of dumping into file to resume it after failure\n"
+	usage += "  --resume <filename>        Continue failed dump from checkpoint file. Arguments and output options are read from it\n"
+	usage += "  --progress                 Show progress in stderr: progress bar on terminal, lines every 10 seconds otherwise\n"
+	usage += "  --summary-json <filename>  Save summary of dumped tables in JSON format\n"
+	usage += "  --skip-validation          Do not check tables, columns and relations against schema of DB before dumping\n"
+	usage += logOptionsHelp()
+	usage += "\n"
+	usage += queryArgumentsHelp()
+	usage += "\n"
+	usage += "Example:\n"
+	usage += "\n"
+	usage += "  sql-dumper dump \"routes:id,name;stations:id,name;stations_for_routes:station_id,route_id,ord\" \\\n"
+	usage += "     2000-2200 \\\n"
+	usage += "     \"routes.id=stations_for_routes.route_id;stations.id=stations_for_routes.station_id\"\n"
+	return usage
+}
+
+func planHelp() string {
+	usage := "Prints DDL, queries with values and names of output files of dump without writing result.\n"
+	usage += "DB is not used for formats without DDL.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper plan [OPTIONS] <tables> <interval> [relations]\n"
+	usage += "\n"
+	usage += "Options:\n"
+	usage += "  --config <filename>        File with settings of connection to DB (default .env)\n"
+	usage += connectionOptionsHelp()
+	usage += "  --timeout <duration>       Time limit for queries of DDL, e.g. 5m. 0 means no limit (default 0)\n"
+	usage += formatOptionsHelp()
+	usage += "  --file <filename>          Specify file to save combined result from all tables, - means stdout. Can't be used with --dir (default result.sql)\n"
+	usage += "  --dir <directory>          Specify directory to save the result in a separate file for every table\n"
+	usage += "  --compress {gzip|zstd}     Compress output files and add extension .gz or .zst to their names.\n"
+	usage += "                             By default it is detected by extension of --file, e.g. result.sql.gz\n"
+	usage += "  --skip-validation          Do not check tables, columns and relations against schema of DB\n"
+	usage += logOptionsHelp()
+	usage += "\n"
+	usage += queryArgumentsHelp()
+	return usage
+}
+
+func estimateHelp() string {
+	usage := "Runs EXPLAIN for every query which would be used for dumping and prints estimated rows, keys and full scans.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper estimate [OPTIONS] <tables> <interval> [relations]\n"
+	usage += "\n"
+	usage += "Options:\n"
+	usage += "  --config <filename>        File with settings of connection to DB (default .env)\n"
+	usage += connectionOptionsHelp()
+	usage += "  --timeout <duration>       Time limit for all queries, e.g. 5m. 0 means no limit (default 0)\n"
+	usage += "  --count                    Run SELECT COUNT(*) for every query in addition to EXPLAIN\n"
+	usage += "  --combined                 Estimate query for combined result instead of queries for every table\n"
+	usage += "  --max-rows <num>           Fail if estimated rows of any query exceed the limit. 0 means no limit (default 0)\n"
+	usage += "  --max-full-scans <num>     Fail if count of full scans exceeds the limit. 0 means no limit (default 0)\n"
+	usage += logOptionsHelp()
+	usage += "\n"
+	usage += queryArgumentsHelp()
+	return usage
+}
+
+func graphHelp() string {
+	usage := "Draws tables and relations of dump as graph. Tables which are not connected with the first table\n"
+	usage += "by relations are highlighted with red color.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper graph [OPTIONS] <tables> <interval> [relations]\n"
+	usage += "\n"
+	usage += "Options:\n"
+	usage += "  --format {dot|mermaid}     Format of graph: Graphviz DOT or Mermaid ER diagram (default dot)\n"
+	usage += "  --all-relations            Add foreign keys from DB which are not used in relations as dashed lines.\n"
+	usage += "                             Tables which are not dumped, but referenced by foreign keys are gray\n"
+	usage += "  --config <filename>        File with settings of connection to DB. It is used only with --all-relations (default .env)\n"
+	usage += connectionOptionsHelp()
+	usage += "  --timeout <duration>       Time limit for reading foreign keys, e.g. 5m. 0 means no limit (default 0)\n"
+	usage += logOptionsHelp()
+	usage += "\n"
+	usage += queryArgumentsHelp()
+	return usage
+}
+
+func describeHelp() string {
+	usage := "Shows columns, keys, foreign keys of table and foreign keys of other tables which reference it.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper describe [OPTIONS] <table>\n"
+	usage += "\n"
+	usage += commonOptionsHelp()
+	return usage
+}
+
+func tablesHelp() string {
+	usage := "Lists tables of database with number of rows estimated by DB.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper tables [OPTIONS]\n"
+	usage += "\n"
+	usage += commonOptionsHelp()
+	return usage
+}
+
+func relationsHelp() string {
+	usage := "Lists foreign keys in format of relations argument of dump.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper relations [OPTIONS] [table]\n"
+	usage += "\n"
+	usage += commonOptionsHelp()
+	usage += "\n"
+	usage += "Arguments:\n"
+	usage += "\n"
+	usage += "  table      Show only foreign keys of table and foreign keys of other tables which reference it\n"
+	return usage
+}
+
+func restoreHelp() string {
+	usage := "Executes statements of SQL files created by dump in database one by one.\n"
+	usage += "Files with extension .gz or .zst are decompressed.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper restore [OPTIONS] <file>...\n"
+	usage += "\n"
+	usage += commonOptionsHelp()
+	return usage
+}
+
+func versionHelp() string {
+	usage := "Prints version.\n"
+	usage += "\n"
+	usage += "Usage: sql-dumper version\n"
+	return usage
+}
+
+func commonOptionsHelp() string {
+	usage := "Options:\n"
+	usage += "  --config <filename>        File with settings of connection to DB (default .env)\n"
+	usage += connectionOptionsHelp()
+	usage += "  --timeout <duration>       Time limit for the command, e.g. 5m. 0 means no limit (default 0)\n"
+	usage += logOptionsHelp()
+	return usage
+}
+
+func connectionOptionsHelp() string {
+	return "  --profile <name>           Section of config file with settings of connection, e.g. prod.\n" +
+		"                             Values of unnamed section are used if they are not defined in section. Environment is ignored\n" +
+		"  --dsn <dsn>                DSN of DB with all settings of connection instead of config, e.g. user:pass@tcp(host:3306)/db?tls=true\n" +
+		"  --ask-password             Ask password of DB in terminal without echo\n" +
+		"  --ssh <destination>        SSH server for tunnel to DB: [user@]host[:port], e.g. deploy@bastion.example.com\n" +
+		"  --ssh-key <filename>       Private key for SSH tunnel (default ~/.ssh/id_ed25519, id_ecdsa, id_rsa and SSH agent)\n" +
+		"  --ssh-known-hosts <file>   File with known hosts to verify key of SSH server (default ~/.ssh/known_hosts)\n"
+}
+
+func logOptionsHelp() string {
+	usage := "  --log-level <level>        Level of logs in stderr: debug, info, warn, error (default warn)\n"
+	usage += "  --log-format {text|json}   Format of logs (default text)\n"
+	return usage
+}
+
+func queryArgumentsHelp() string {
+	usage := "Arguments:\n"
+	usage += "\n"
+	usage += "  tables     List of tables and columns to dump: table1:column11,column12,...,column1N;table2:column21;...\n"
+	usage += "             Table can be used several times with aliases: table@alias1:column1;table@alias2:column1\n"
+	usage += "             Table of another database is qualified by name of database: db.table:column1\n"
+	usage += "  interval   Interval of values for the first column in the first table to select from DB: int-int\n"
+	usage += "  relations  List of relations between chosen tables and columns:\n"
+	usage += "             table1.column11=table2.column21;table2.column22=table3.column31\n"
+	usage += "             Aliased tables are referenced by alias: alias1.column1=table2.column21\n"
+	usage += "             Qualified tables are referenced with name of database: db.table.column1=table2.column21\n"
+	usage += "\n"
+	usage += "  Names which contain any of ;:,.=@ are quoted with double quotes or backticks: \"my.table\":\"column,1\".\n"
+	usage += "  Quote inside of quoted name is doubled: \"my\"\"table\".\n"
+	return usage
+}
+
+// formatOptionsHelp describes the option of format and flags of all registered formats.
+func formatOptionsHelp() string {
+	usage := optionHelp("--format {"+strings.Join(formatNames(), "|")+"}", "Format of output format (default "+defaultFormat+")")
+	names := make(map[string]bool)
+	for _, name := range formatNames() {
+		format, _ := dumper.LookupFormat(name)
+		for _, formatFlag := range format.Flags {
+			if names[formatFlag.Name] {
+				continue
+			}
+			names[formatFlag.Name] = true
+			description := formatFlag.Usage
+			if formatFlag.Default != "" {
+				description += " (default " + formatFlag.Default + ")"
+			}
+			usage += optionHelp("--"+formatFlag.Name, description)
+		}
+	}
+	return usage
+}
+
+// formatNames returns names of registered formats with the default format first.
+func formatNames() []string {
+	names := []string{defaultFormat}
+	for _, name := range dumper.Formats() {
+		if name != defaultFormat {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func optionHelp(option, description string) string {
+	return fmt.Sprintf("  %-26s %s\n", option, description)
+}
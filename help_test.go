@@ -0,0 +1,16 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormatOptionsHelp(t *testing.T) {
+	expected := "  --format {sql|csv|simple}  Format of output format (default sql)\n" +
+		"  --csv-delimiter            Sets delimiter of values in CSV (default ,)\n"
+	assert.Equal(t, expected, formatOptionsHelp())
+}
+
+func TestFormatNamesDefaultFirst(t *testing.T) {
+	assert.Equal(t, []string{"sql", "csv", "simple"}, formatNames())
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"strings"
+)
+
+// logger is used by command line interface. It discards everything until it is configured by main.
+var logger = slog.New(slog.NewTextHandler(ioutil.Discard, nil))
+
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// NewLogger builds logger with level (debug, info, warn, error) and format (text, json)
+func NewLogger(out io.Writer, level string, format string) (*slog.Logger, error) {
+	slogLevel, ok := logLevels[strings.ToLower(level)]
+	if !ok {
+		return nil, fmt.Errorf("Unknown log level '%s'. Available levels: debug, info, warn, error", level)
+	}
+	handlerOptions := &slog.HandlerOptions{Level: slogLevel}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(out, handlerOptions)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(out, handlerOptions)), nil
+	}
+	return nil, fmt.Errorf("Unknown log format '%s'. Available formats: text, json", format)
+}
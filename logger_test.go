@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/rnixik/sql-dumper/dumper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	textLogger, err := NewLogger(out, "info", "text")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	textLogger.Debug("Hidden message")
+	textLogger.Info("Some message", "table", "routes")
+	assert.NotContains(t, out.String(), "Hidden message")
+	assert.Contains(t, out.String(), "level=INFO msg=\"Some message\" table=routes")
+
+	out.Reset()
+	jsonLogger, err := NewLogger(out, "DEBUG", "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	jsonLogger.Debug("Some message", "rows", 3)
+	record := make(map[string]interface{})
+	err = json.Unmarshal(out.Bytes(), &record)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "DEBUG", record["level"])
+	assert.Equal(t, "Some message", record["msg"])
+	assert.Equal(t, float64(3), record["rows"])
+}
+
+func TestNewLoggerError(t *testing.T) {
+	_, err := NewLogger(&bytes.Buffer{}, "verbose", "text")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	_, err = NewLogger(&bytes.Buffer{}, "info", "xml")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestRunLogging(t *testing.T) {
+	out := &bytes.Buffer{}
+	defaultLogger := logger
+	logger, _ = NewLogger(out, "debug", "text")
+	defer func() {
+		logger = defaultLogger
+	}()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
+	}
+
+	expectSchemaQuery(mock, "some_table", "id", "bigint(20)")
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	err = Run(context.Background(), dbConnectMock, []string{"some_table:id", "1-2"}, testConnection, "sql", dumper.NewMemoryFileWriter(), "test_example.sql", "", nil, &DumpOptions{})
+	os.Setenv("DB_NAME", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	logs := out.String()
+	assert.Contains(t, logs, "msg=\"Connection settings are read\"")
+	assert.Contains(t, logs, "msg=\"Query finished\"")
+	assert.Contains(t, logs, "rows=1 duration=")
+	assert.Contains(t, logs, "msg=\"Rows are written into file\" table=some_table file=test_example.sql rows=1")
+	if strings.Contains(logs, "password") || strings.Contains(logs, "root:root") {
+		t.Errorf("Expected logs without password, but got:\n%s", logs)
+	}
+}
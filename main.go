@@ -1,31 +1,385 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"flag"
 	"fmt"
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/jmoiron/sqlx"
+	"github.com/rnixik/sql-dumper/dumper"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
-func dbConnect(conset *ConnectionSettings) (db *sqlx.DB, err error) {
-	return sqlx.Open(conset.driver, conset.dsn())
+// defaultFormat is the format of output used when --format is not set.
+const defaultFormat = "sql"
+
+func dbConnect(conset *ConnectionSettings) (db *sql.DB, err error) {
+	connector, err := conset.newConnector()
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// version is set at build time: go build -ldflags "-X main.version=1.0.0"
+var version = "dev"
+
+// command is subcommand of command line interface
+type command struct {
+	run     func(args []string) error
+	failure string
+}
+
+var commands = map[string]*command{
+	"dump":      {dump, "Dumping failed"},
+	"plan":      {plan, "Planning failed"},
+	"estimate":  {estimate, "Estimating failed"},
+	"graph":     {graph, "Drawing graph failed"},
+	"describe":  {describe, "Describing failed"},
+	"tables":    {tables, "Listing tables failed"},
+	"relations": {relations, "Listing relations failed"},
+	"restore":   {restore, "Restoring failed"},
+	"version":   {printVersion, "Printing version failed"},
 }
 
 func main() {
-	configFile := flag.String("config", ".env", "File with settings of connection to DB")
-	format := flag.String("format", "sql", "Output format: sql, csv, simple")
-	csvDelimiter := flag.String("csv-delimiter", ",", "Delimiter for csv format")
-	dstFile := flag.String("file", "", "Filename for single output file")
-	dstDir := flag.String("dir", "", "Output directory for multiple output files")
-	flag.Usage = showHelp
-	flag.Parse()
+	args := os.Args[1:]
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		showHelp()
+		return
+	}
+	if args[0] == "help" {
+		if len(args) > 1 {
+			showCommandHelp(args[1])
+			return
+		}
+		showHelp()
+		return
+	}
+	name := "dump"
+	if _, ok := commands[args[0]]; ok {
+		name, args = args[0], args[1:]
+	}
+	err := commands[name].run(args)
+	if err != nil {
+		logger.Error(commands[name].failure, "error", err)
+		os.Exit(1)
+	}
+}
+
+// commonFlags are flags of all commands which connect to DB
+type commonFlags struct {
+	configFile    *string
+	profile       *string
+	dsn           *string
+	askPassword   *bool
+	ssh           *string
+	sshKey        *string
+	sshKnownHosts *string
+	timeout       *time.Duration
+	logLevel      *string
+	logFormat     *string
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		showCommandHelp(name)
+	}
+	return flags
+}
+
+func defineCommonFlags(flags *flag.FlagSet, timeoutUsage string) *commonFlags {
+	return &commonFlags{
+		configFile:    flags.String("config", ".env", "File with settings of connection to DB"),
+		profile:       flags.String("profile", "", "Section of config file with settings of connection"),
+		dsn:           flags.String("dsn", "", "DSN of DB with all settings of connection"),
+		askPassword:   flags.Bool("ask-password", false, "Ask password of DB in terminal"),
+		ssh:           flags.String("ssh", "", "SSH server for tunnel to DB: user@host:port"),
+		sshKey:        flags.String("ssh-key", "", "Private key for SSH tunnel"),
+		sshKnownHosts: flags.String("ssh-known-hosts", "", "File with known hosts of SSH"),
+		timeout:       flags.Duration("timeout", 0, timeoutUsage),
+		logLevel:      flags.String("log-level", "warn", "Log level: debug, info, warn, error"),
+		logFormat:     flags.String("log-format", "text", "Log format: text, json"),
+	}
+}
+
+// connectionOptions returns options of connection from parsed flags
+func (common *commonFlags) connectionOptions() *ConnectionOptions {
+	return &ConnectionOptions{
+		configFile:    *common.configFile,
+		profile:       *common.profile,
+		dsn:           *common.dsn,
+		askPassword:   *common.askPassword,
+		ssh:           *common.ssh,
+		sshKey:        *common.sshKey,
+		sshKnownHosts: *common.sshKnownHosts,
+	}
+}
+
+func dump(args []string) error {
+	flags := newFlagSet("dump")
+	common := defineCommonFlags(flags, "Time limit for the whole dump")
+	format := flags.String("format", defaultFormat, "Output format: "+strings.Join(formatNames(), ", "))
+	formatFlags := defineFormatFlags(flags)
+	dstFile := flags.String("file", "", "Filename for single output file")
+	dstDir := flags.String("dir", "", "Output directory for multiple output files")
+	compress := flags.String("compress", "", "Compression of output files: gzip, zstd")
+	dedupSpillLimit := flags.Int("dedup-spill-limit", 0, "Number of primary keys per table to keep in memory before moving them to disk")
+	dedupSpillDir := flags.String("dedup-spill-dir", "", "Directory for primary keys moved to disk")
+	mask := flags.String("mask", "", "Rules of masking values of columns")
+	maskFile := flags.String("mask-file", "", "File with masking rules")
+	maskSecret := flags.String("mask-secret", os.Getenv("MASK_SECRET"), "Secret for pseudo masking method")
+	dryRun := flags.Bool("dry-run", false, "Print DDL, queries and names of output files without writing result")
+	jobs := flags.Int("jobs", 1, "Number of queries to run concurrently")
+	queryTimeout := flags.Duration("query-timeout", 0, "Time limit for every query")
+	keepPartial := flags.Bool("keep-partial", false, "Keep output of failed dump with suffix .partial")
+	overwrite := flags.Bool("overwrite", false, "Replace existing output files")
+	appendFiles := flags.Bool("append", false, "Append to existing output files")
+	chunkSize := flags.Int64("chunk-size", 0, "Number of values of the first column to select in one chunk")
+	checkpointFile := flags.String("checkpoint", "", "File to save progress of dumping")
+	resume := flags.String("resume", "", "Checkpoint file to continue failed dump")
+	progress := flags.Bool("progress", false, "Show progress in stderr")
+	summaryFile := flags.String("summary-json", "", "File to save summary in JSON format")
+	skipValidation := flags.Bool("skip-validation", false, "Do not check tables, columns and relations against schema")
+	flags.Parse(args)
+
+	setupLogger(*common.logLevel, *common.logFormat)
+
+	maskingRules, err := getMaskingRules(*mask, *maskFile, *maskSecret)
+	if err != nil {
+		return err
+	}
+
+	compression, err := getCompression(*compress, *dstFile)
+	if err != nil {
+		return err
+	}
+	mode, err := getWriteMode(*overwrite, *appendFiles)
+	if err != nil {
+		return err
+	}
+	fw := dumper.NewOsFileWriter(dumper.WithCompression(compression), dumper.WithWriteMode(mode), dumper.WithFileLogger(logger))
+	options := &DumpOptions{
+		dedupSpillLimit: *dedupSpillLimit,
+		dedupSpillDir:   *dedupSpillDir,
+		maskingRules:    maskingRules,
+		maskingSecret:   *maskSecret,
+		dryRun:          *dryRun,
+		jobs:            *jobs,
+		queryTimeout:    *queryTimeout,
+		keepPartial:     *keepPartial,
+		chunkSize:       *chunkSize,
+		checkpointFile:  *checkpointFile,
+		summaryFile:     *summaryFile,
+		skipValidation:  *skipValidation,
+	}
+	if *progress {
+		options.progress, options.progressOut = newProgressReporter(os.Stderr)
+	} else if *summaryFile != "" {
+		options.progress, options.progressOut = dumper.NewProgressReporter(ioutil.Discard, false, time.Hour), ioutil.Discard
+	}
+
+	ctx, cancel := newContext(*common.timeout)
+	defer cancel()
+
+	if *resume != "" {
+		return RunResume(ctx, dbConnect, *resume, flags.Args(), common.connectionOptions(), fw, options)
+	}
+	return Run(ctx, dbConnect, flags.Args(), common.connectionOptions(), *format, fw, *dstFile, *dstDir, getFormatFlags(flags, formatFlags), options)
+}
+
+func plan(args []string) error {
+	flags := newFlagSet("plan")
+	common := defineCommonFlags(flags, "Time limit for queries of DDL")
+	format := flags.String("format", defaultFormat, "Output format: "+strings.Join(formatNames(), ", "))
+	formatFlags := defineFormatFlags(flags)
+	dstFile := flags.String("file", "", "Filename for single output file")
+	dstDir := flags.String("dir", "", "Output directory for multiple output files")
+	compress := flags.String("compress", "", "Compression of output files: gzip, zstd")
+	skipValidation := flags.Bool("skip-validation", false, "Do not check tables, columns and relations against schema")
+	flags.Parse(args)
+
+	setupLogger(*common.logLevel, *common.logFormat)
+
+	compression, err := getCompression(*compress, *dstFile)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := newContext(*common.timeout)
+	defer cancel()
+
+	options := &DumpOptions{dryRun: true, jobs: 1, skipValidation: *skipValidation}
+	return Run(ctx, dbConnect, flags.Args(), common.connectionOptions(), *format, dumper.NewOsFileWriter(dumper.WithCompression(compression), dumper.WithFileLogger(logger)), *dstFile, *dstDir, getFormatFlags(flags, formatFlags), options)
+}
+
+func estimate(args []string) error {
+	flags := newFlagSet("estimate")
+	common := defineCommonFlags(flags, "Time limit for all queries")
+	count := flags.Bool("count", false, "Run SELECT COUNT(*) for every query")
+	combined := flags.Bool("combined", false, "Estimate query for combined result")
+	maxRows := flags.Int64("max-rows", 0, "Fail if estimated rows of any query exceed the limit")
+	maxFullScans := flags.Int("max-full-scans", 0, "Fail if count of full scans exceeds the limit")
+	flags.Parse(args)
+
+	setupLogger(*common.logLevel, *common.logFormat)
+
+	options := &dumper.EstimateOptions{
+		Combined:     *combined,
+		Count:        *count,
+		MaxRows:      *maxRows,
+		MaxFullScans: *maxFullScans,
+	}
+
+	ctx, cancel := newContext(*common.timeout)
+	defer cancel()
+
+	return RunEstimate(ctx, dbConnect, flags.Args(), common.connectionOptions(), options, os.Stdout)
+}
+
+func graph(args []string) error {
+	flags := newFlagSet("graph")
+	common := defineCommonFlags(flags, "Time limit for reading foreign keys")
+	format := flags.String("format", "dot", "Graph format: dot, mermaid")
+	allRelations := flags.Bool("all-relations", false, "Add foreign keys from DB")
+	flags.Parse(args)
+
+	setupLogger(*common.logLevel, *common.logFormat)
+
+	ctx, cancel := newContext(*common.timeout)
+	defer cancel()
+
+	return RunGraph(ctx, dbConnect, flags.Args(), common.connectionOptions(), *format, *allRelations, os.Stdout)
+}
 
-	fw := NewOsFileWriter()
+func describe(args []string) error {
+	return runSchemaCommand("describe", args, RunDescribe)
+}
+
+func tables(args []string) error {
+	return runSchemaCommand("tables", args, RunTables)
+}
+
+func relations(args []string) error {
+	return runSchemaCommand("relations", args, RunRelations)
+}
+
+func restore(args []string) error {
+	return runSchemaCommand("restore", args, RunRestore)
+}
+
+// runSchemaCommand parses common flags of command and runs it with output into stdout
+func runSchemaCommand(name string, args []string, run func(ctx context.Context, dbConnect dbConnector, argsTail []string, connOptions *ConnectionOptions, out io.Writer) error) error {
+	flags := newFlagSet(name)
+	common := defineCommonFlags(flags, "Time limit for the command")
+	flags.Parse(args)
+
+	setupLogger(*common.logLevel, *common.logFormat)
+
+	ctx, cancel := newContext(*common.timeout)
+	defer cancel()
+
+	return run(ctx, dbConnect, flags.Args(), common.connectionOptions(), os.Stdout)
+}
 
-	err := Run(dbConnect, flag.Args(), *configFile, *format, fw, *dstFile, *dstDir, *csvDelimiter)
+func printVersion(args []string) error {
+	flags := newFlagSet("version")
+	flags.Parse(args)
+	fmt.Printf("sql-dumper %s\n", version)
+	return nil
+}
+
+// defineFormatFlags defines flags of all registered formats.
+// Flags with the same name can be shared by several formats.
+func defineFormatFlags(flags *flag.FlagSet) map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range dumper.Formats() {
+		format, _ := dumper.LookupFormat(name)
+		for _, formatFlag := range format.Flags {
+			if names[formatFlag.Name] {
+				continue
+			}
+			names[formatFlag.Name] = true
+			flags.String(formatFlag.Name, formatFlag.Default, formatFlag.Usage)
+		}
+	}
+	return names
+}
+
+// getCompression returns compression from flag or detects it by extension of output file, e.g. result.sql.gz
+func getCompression(name string, dstFile string) (*dumper.Compression, error) {
+	if name != "" {
+		return dumper.LookupCompression(name)
+	}
+	return dumper.DetectCompression(dstFile), nil
+}
+
+// getWriteMode returns mode of writing into existing output files from flags --overwrite and --append
+func getWriteMode(overwrite bool, appendFiles bool) (dumper.WriteMode, error) {
+	switch {
+	case overwrite && appendFiles:
+		return dumper.WriteCreate, fmt.Errorf("Flags --overwrite and --append can not be used together")
+	case overwrite:
+		return dumper.WriteOverwrite, nil
+	case appendFiles:
+		return dumper.WriteAppend, nil
+	}
+	return dumper.WriteCreate, nil
+}
+
+// getFormatFlags returns values of format flags which are set explicitly
+func getFormatFlags(flags *flag.FlagSet, names map[string]bool) map[string]string {
+	values := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		if names[f.Name] {
+			values[f.Name] = f.Value.String()
+		}
+	})
+	return values
+}
+
+// setupLogger configures logger which writes into stderr
+func setupLogger(level string, format string) {
+	configuredLogger, err := NewLogger(os.Stderr, level, format)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	logger = configuredLogger
+}
+
+// newProgressReporter returns reporter into file which draws progress bar on terminal
+func newProgressReporter(f *os.File) (*dumper.ProgressReporter, io.Writer) {
+	if isTerminal(f) {
+		return dumper.NewProgressReporter(f, true, 200*time.Millisecond), f
+	}
+	return dumper.NewProgressReporter(f, false, 10*time.Second), f
+}
+
+// isTerminal returns true if file is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// newContext returns context which is cancelled by SIGINT, SIGTERM or after timeout
+func newContext(timeout time.Duration) (ctx context.Context, cancel context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancelTimeout()
+		stop()
+	}
 }
@@ -1,111 +1,362 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"gopkg.in/ini.v1"
+	"github.com/rnixik/sql-dumper/dumper"
+	"io"
 	"os"
+	"strings"
+	"time"
 )
 
+// DumpOptions contains settings of dumping from command line which are not part of query
+type DumpOptions struct {
+	dedupSpillLimit int
+	dedupSpillDir   string
+	maskingRules    []*dumper.MaskingRule
+	maskingSecret   string
+	dryRun          bool
+	jobs            int
+	queryTimeout    time.Duration
+	keepPartial     bool
+	chunkSize       int64
+	checkpointFile  string
+	checkpoint      *dumper.Checkpoint
+	progress        *dumper.ProgressReporter
+	progressOut     io.Writer
+	summaryFile     string
+	skipValidation  bool
+}
+
+type dbConnector func(conset *ConnectionSettings) (db *sql.DB, err error)
+
 // Run is entry point for application
-func Run(dbConnect dbConnector, argsTail []string, configFile string, format string, fw FileWriter, dstFile string, dstDir string, csvDelimiter string) (err error) {
+func Run(ctx context.Context, dbConnect dbConnector, argsTail []string, connOptions *ConnectionOptions, format string, fw dumper.FileWriter, dstFile string, dstDir string, formatFlags map[string]string, options *DumpOptions) (err error) {
 	if len(argsTail) != 2 && len(argsTail) != 3 {
-		showHelp()
+		showCommandHelp("dump")
 		return
 	}
 
-	conset, err := getConnectionSettings(configFile)
+	conset, err := getConnectionSettings(connOptions)
 	if err != nil {
 		return err
 	}
+	logger.Info("Connection settings are read", "driver", conset.driver, "host", conset.dbhost, "user", conset.user, "database", conset.dbname)
 
-	tablesPart := argsTail[0]
-	intervalPart := argsTail[1]
-	relationsPart := ""
-	if len(argsTail) == 3 {
-		relationsPart = argsTail[2]
+	query, err := parseQueryArgs(argsTail)
+	if err != nil {
+		return err
 	}
 
-	query, err := ParseRequest(tablesPart, intervalPart, relationsPart)
+	outputFormat, err := dumper.LookupFormat(format)
 	if err != nil {
 		return err
 	}
+	writer, combined, err := outputFormat.Writer(fw, dstFile, dstDir, formatFlags)
+	if err != nil {
+		return err
+	}
+	dumpOptions := getDumpOptions(options, combined)
+
+	var db *sql.DB
+	if !options.dryRun || outputFormat.DDL {
+		db, err = dbConnect(conset)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if !options.skipValidation {
+			err = dumper.Validate(ctx, db, query)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-	writer, combined := getWriterAndCombinedMode(format, fw, dstFile, dstDir, csvDelimiter)
+	if options.dryRun {
+		return dumper.DryRun(ctx, db, query, writer, os.Stdout, dumpOptions...)
+	}
 
-	err = query.QueryResult(dbConnect, conset, writer, combined)
+	checkpoint := options.checkpoint
+	if options.checkpointFile != "" && checkpoint == nil {
+		checkpoint, err = dumper.NewCheckpoint(options.checkpointFile, argsTail, format, dstFile, dstDir, formatFlags, options.chunkSize)
+		if err != nil {
+			return err
+		}
+	}
+	if checkpoint != nil {
+		dumpOptions = append(dumpOptions, dumper.WithCheckpoint(checkpoint, fw))
+	}
+
+	err = dumper.Dump(ctx, db, query, writer, dumpOptions...)
+	// Output of failed dump is closed by cleanup, so temporary files are not renamed into target files
+	if closer, ok := fw.(io.Closer); ok && (err == nil || checkpoint != nil) {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if options.progress != nil {
+		summaryErr := reportSummary(options.progress.Summary(err), options.summaryFile, options.progressOut)
+		if err == nil {
+			err = summaryErr
+		}
+	}
+	if err != nil && checkpoint != nil {
+		return fmt.Errorf("%s. Use --resume %s to continue dumping", err, checkpoint.Filename())
+	}
 	if err != nil {
+		if cleaner, ok := fw.(dumper.PartialCleaner); ok {
+			logger.Info("Cleaning up partial output", "keep_partial", options.keepPartial)
+			if cleanupErr := cleaner.CleanupPartial(options.keepPartial); cleanupErr != nil {
+				return fmt.Errorf("%s. Fail to clean up partial output: %s", err, cleanupErr)
+			}
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("Dumping is interrupted: %s", ctx.Err())
+		}
 		return err
 	}
 	return nil
 }
 
-func getWriterAndCombinedMode(format string, fw FileWriter, dstFile string, dstDir string, csvDelimiter string) (writer DataWriter, combined bool) {
-	combined = true
-	writer = &SimpleWriter{}
-	if format == "sql" {
-		combined = false
-		if dstFile == "" && dstDir == "" {
-			dstFile = "result.sql"
-		}
-		writer = NewSqlWriter(fw, dstFile, dstDir)
-	} else if format == "csv" {
-		if dstFile == "" && dstDir == "" {
-			dstFile = "result.csv"
+func getDumpOptions(options *DumpOptions, combined bool) []dumper.Option {
+	dumpOptions := []dumper.Option{
+		dumper.WithJobs(options.jobs),
+		dumper.WithQueryTimeout(options.queryTimeout),
+		dumper.WithChunkSize(options.chunkSize),
+		dumper.WithDedupSpill(options.dedupSpillLimit, options.dedupSpillDir),
+		dumper.WithMasking(options.maskingRules, options.maskingSecret),
+		dumper.WithProgress(options.progress),
+		dumper.WithLogger(logger),
+	}
+	if combined {
+		dumpOptions = append(dumpOptions, dumper.WithCombined())
+	}
+	return dumpOptions
+}
+
+func reportSummary(summary *dumper.DumpSummary, summaryFile string, out io.Writer) error {
+	dumper.PrintSummary(out, summary)
+	if summaryFile != "" {
+		return dumper.WriteSummaryJSON(summaryFile, summary)
+	}
+	return nil
+}
+
+// RunResume continues failed dump using settings and progress from checkpoint file
+func RunResume(ctx context.Context, dbConnect dbConnector, checkpointFile string, argsTail []string, connOptions *ConnectionOptions, fw dumper.FileWriter, options *DumpOptions) (err error) {
+	checkpoint, err := dumper.ReadCheckpoint(checkpointFile)
+	if err != nil {
+		return err
+	}
+	if len(argsTail) > 0 && strings.Join(argsTail, "\n") != strings.Join(checkpoint.Args, "\n") {
+		return fmt.Errorf("Arguments differ from arguments of dump in checkpoint: %s", strings.Join(checkpoint.Args, " "))
+	}
+	logger.Info("Resuming dump from checkpoint", "checkpoint", checkpointFile, "tables", len(checkpoint.Tables))
+	options.chunkSize = checkpoint.ChunkSize
+	options.checkpoint = checkpoint
+	return Run(ctx, dbConnect, checkpoint.Args, connOptions, checkpoint.Format, fw, checkpoint.File, checkpoint.Dir, checkpoint.FormatFlags, options)
+}
+
+// RunEstimate is entry point for estimate command
+func RunEstimate(ctx context.Context, dbConnect dbConnector, argsTail []string, connOptions *ConnectionOptions, options *dumper.EstimateOptions, out io.Writer) (err error) {
+	if len(argsTail) != 2 && len(argsTail) != 3 {
+		showCommandHelp("estimate")
+		return
+	}
+
+	conset, err := getConnectionSettings(connOptions)
+	if err != nil {
+		return err
+	}
+
+	query, err := parseQueryArgs(argsTail)
+	if err != nil {
+		return err
+	}
+
+	db, err := dbConnect(conset)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	estimates, err := dumper.Estimate(ctx, db, query, options)
+	if err != nil {
+		return err
+	}
+	dumper.PrintEstimates(out, estimates)
+	return dumper.CheckEstimates(estimates, options)
+}
+
+// RunGraph writes tables and relations of query as graph in DOT or Mermaid format.
+// Foreign keys are read from DB only with allRelations.
+func RunGraph(ctx context.Context, dbConnect dbConnector, argsTail []string, connOptions *ConnectionOptions, graphFormat string, allRelations bool, out io.Writer) (err error) {
+	if len(argsTail) != 2 && len(argsTail) != 3 {
+		showCommandHelp("graph")
+		return
+	}
+	if graphFormat != "dot" && graphFormat != "mermaid" {
+		return fmt.Errorf("Unknown graph format '%s'. Available formats: dot, mermaid", graphFormat)
+	}
+
+	query, err := parseQueryArgs(argsTail)
+	if err != nil {
+		return err
+	}
+
+	var foreignKeys []*dumper.ForeignKey
+	if allRelations {
+		db, err := connect(dbConnect, connOptions)
+		if err != nil {
+			return err
 		}
-		if dstDir != "" {
-			combined = false
+		defer db.Close()
+		foreignKeys, err = dumper.ListRelations(ctx, db, "")
+		if err != nil {
+			return err
 		}
-		writer = NewCsvWriter(fw, dstFile, dstDir, csvDelimiter)
 	}
-	return
+
+	graph := dumper.NewGraph(query, foreignKeys)
+	if unreachable := graph.Unreachable(); len(unreachable) > 0 {
+		logger.Warn("Tables are not connected with the first table by relations", "tables", strings.Join(unreachable, ","))
+	}
+	if graphFormat == "mermaid" {
+		return graph.WriteMermaid(out)
+	}
+	return graph.WriteDOT(out)
+}
+
+// RunDescribe prints columns, keys and foreign keys of table
+func RunDescribe(ctx context.Context, dbConnect dbConnector, argsTail []string, connOptions *ConnectionOptions, out io.Writer) (err error) {
+	if len(argsTail) != 1 {
+		showCommandHelp("describe")
+		return
+	}
+	db, err := connect(dbConnect, connOptions)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	description, err := dumper.DescribeTable(ctx, db, argsTail[0])
+	if err != nil {
+		return err
+	}
+	dumper.PrintTableDescription(out, description)
+	return nil
+}
+
+// RunTables prints tables of database with estimated rows
+func RunTables(ctx context.Context, dbConnect dbConnector, argsTail []string, connOptions *ConnectionOptions, out io.Writer) (err error) {
+	if len(argsTail) != 0 {
+		showCommandHelp("tables")
+		return
+	}
+	db, err := connect(dbConnect, connOptions)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tables, err := dumper.ListTables(ctx, db)
+	if err != nil {
+		return err
+	}
+	dumper.PrintTables(out, tables)
+	return nil
 }
 
-func getConnectionSettings(configFile string) (*ConnectionSettings, error) {
-	if os.Getenv("DB_NAME") == "" {
-		cfg, err := ini.Load(configFile)
+// RunRelations prints foreign keys of database or of one table in format of relations argument
+func RunRelations(ctx context.Context, dbConnect dbConnector, argsTail []string, connOptions *ConnectionOptions, out io.Writer) (err error) {
+	if len(argsTail) > 1 {
+		showCommandHelp("relations")
+		return
+	}
+	table := ""
+	if len(argsTail) == 1 {
+		table = argsTail[0]
+	}
+	db, err := connect(dbConnect, connOptions)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	foreignKeys, err := dumper.ListRelations(ctx, db, table)
+	if err != nil {
+		return err
+	}
+	dumper.PrintRelations(out, foreignKeys)
+	return nil
+}
+
+// RunRestore executes SQL files in DB one by one
+func RunRestore(ctx context.Context, dbConnect dbConnector, argsTail []string, connOptions *ConnectionOptions, out io.Writer) (err error) {
+	if len(argsTail) == 0 {
+		showCommandHelp("restore")
+		return
+	}
+	db, err := connect(dbConnect, connOptions)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	for _, filename := range argsTail {
+		statements, err := restoreFile(ctx, db, filename)
 		if err != nil {
-			return nil, fmt.Errorf("Empty DB_NAME in environment and fail to read config file: %v", err)
+			return fmt.Errorf("Fail to restore '%s': %s", filename, err)
 		}
-		cfgSection := cfg.Section("")
-		os.Setenv("DB_USER", cfgSection.Key("DB_USER").String())
-		os.Setenv("DB_PASSWORD", cfgSection.Key("DB_PASSWORD").String())
-		os.Setenv("DB_NAME", cfgSection.Key("DB_NAME").String())
-		os.Setenv("DB_HOST", cfgSection.Key("DB_HOST").String())
-	}
-
-	return &ConnectionSettings{
-		driver:   "mysql",
-		user:     os.Getenv("DB_USER"),
-		password: os.Getenv("DB_PASSWORD"),
-		dbname:   os.Getenv("DB_NAME"),
-		dbhost:   os.Getenv("DB_HOST"),
-	}, nil
+		logger.Info("File is restored", "file", filename, "statements", statements)
+		fmt.Fprintf(out, "Restored %d statements from %s\n", statements, filename)
+	}
+	return nil
 }
 
-func showHelp() {
-	usage := "Dumps data from DB.\n"
-	usage += "\n"
-	usage += "Usage: sql-dumper [OPTIONS] <tables> <interval> [relations]\n"
-	usage += "\n"
-	usage += "Options:\n"
-	usage += "  --config <filename>        File with settings of connection to DB.\n"
-	usage += "                             It will be used if environment variable DB_NAME is not defined (default .env)\n"
-	usage += "  --format {sql|csv|simple}  Format of output format (default sql)\n"
-	usage += "  --csv-delimiter            Sets delimiter of values in CSV (default ,)\n"
-	usage += "  --file <filename>          Specify file to save combined result from all tables. Can't be used with --dir (default result.sql)\n"
-	usage += "  --dir <directory>          Specify directory to save the result in a separate file for every table\n"
-	usage += "\n"
-	usage += "Arguments:\n"
-	usage += "\n"
-	usage += "  tables     List of tables and columns to dump: table1:column11,column12,...,column1N;table2:column21;...\n"
-	usage += "  interval   Interval of values for the first column in the first table to select from DB: int-int\n"
-	usage += "  relations  List of relations between chosen tables and columns:\n"
-	usage += "             table1.column11=table2.column21;table2.column22=table3.column31\n"
-	usage += "\n"
-	usage += "Example:\n"
-	usage += "\n"
-	usage += "  sql-dumper \"routes:id,name;stations:id,name;stations_for_routes:station_id,route_id,ord\" \\\n"
-	usage += "     2000-2200 \\\n"
-	usage += "     \"routes.id=stations_for_routes.route_id;stations.id=stations_for_routes.station_id\"\n"
-	fmt.Fprintln(os.Stderr, usage)
+// restoreFile executes statements of file, compressed file is detected by extension, e.g. result.sql.gz
+func restoreFile(ctx context.Context, db *sql.DB, filename string) (int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	compression := dumper.DetectCompression(filename)
+	if compression == nil {
+		return dumper.Restore(ctx, db, f, dumper.WithLogger(logger))
+	}
+	reader, err := compression.NewReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("Fail to decompress %s: %s", compression.Name, err)
+	}
+	defer reader.Close()
+	logger.Debug("File is decompressed for restoring", "file", filename, "compression", compression.Name)
+	return dumper.Restore(ctx, db, reader, dumper.WithLogger(logger))
+}
+
+func parseQueryArgs(argsTail []string) (query *dumper.Query, err error) {
+	tablesPart := argsTail[0]
+	intervalPart := argsTail[1]
+	relationsPart := ""
+	if len(argsTail) == 3 {
+		relationsPart = argsTail[2]
+	}
+	return dumper.ParseRequest(tablesPart, intervalPart, relationsPart)
+}
+
+func getMaskingRules(mask string, maskFile string, maskSecret string) (rules []*dumper.MaskingRule, err error) {
+	rules, err = dumper.ParseMaskingRules(mask)
+	if err != nil {
+		return nil, err
+	}
+	if maskFile != "" {
+		fileRules, err := dumper.ReadMaskingRulesFile(maskFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+	if dumper.HasPseudoRules(rules) && maskSecret == "" {
+		return nil, fmt.Errorf("Masking method 'pseudo' requires secret: use --mask-secret or MASK_SECRET in environment")
+	}
+	return rules, nil
 }
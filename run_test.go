@@ -1,20 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
 	"fmt"
-	"github.com/jmoiron/sqlx"
+	"github.com/rnixik/sql-dumper/dumper"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+	"io/ioutil"
 	"os"
-	"reflect"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
+// testConnection reads settings of connection from example of config
+var testConnection = &ConnectionOptions{configFile: ".env.example"}
+
 func TestRunErrorArguments(t *testing.T) {
-	dbConnect := func(conset *ConnectionSettings) (db *sqlx.DB, err error) {
+	dbConnect := func(conset *ConnectionSettings) (db *sql.DB, err error) {
 		return nil, nil
 	}
-	err := Run(dbConnect, []string{}, "", "", NewTestFileWriter(), "", "", ",")
+	err := Run(context.Background(), dbConnect, []string{}, nil, "", dumper.NewMemoryFileWriter(), "", "", nil, &DumpOptions{})
 	if err != nil {
 		t.Errorf("Expected help, but got error: %s", err)
 		return
@@ -27,12 +36,12 @@ func TestRun(t *testing.T) {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer mockDB.Close()
-	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
 
-	dbConnectMock := func(conset *ConnectionSettings) (db *sqlx.DB, err error) {
-		return sqlxDB, nil
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
 	}
 
+	expectSchemaQuery(mock, "some_table", "id", "bigint(20)")
 	mock.ExpectQuery("DESCRIBE `some_table`").
 		WillReturnRows(
 			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
@@ -41,13 +50,17 @@ func TestRun(t *testing.T) {
 	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
 		WithArgs(1, 2).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectClose()
 
-	err = Run(dbConnectMock, []string{"some_table:id", "1-2"}, ".env.example", "sql", NewTestFileWriter(), "test_example.sql", "", ",")
+	err = Run(context.Background(), dbConnectMock, []string{"some_table:id", "1-2"}, testConnection, "sql", dumper.NewMemoryFileWriter(), "test_example.sql", "", nil, &DumpOptions{})
 	os.Setenv("DB_NAME", "")
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err)
 		return
 	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expected closed connection: %s", err)
+	}
 }
 
 func TestRunDbError(t *testing.T) {
@@ -56,15 +69,15 @@ func TestRunDbError(t *testing.T) {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 	}
 	defer mockDB.Close()
-	sqlxDB := sqlx.NewDb(mockDB, "sqlmock")
 
-	dbConnectMock := func(conset *ConnectionSettings) (db *sqlx.DB, err error) {
-		return sqlxDB, nil
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
 	}
 
+	expectSchemaQuery(mock, "some_table", "id", "bigint(20)")
 	mock.ExpectQuery("DESCRIBE `some_table`").WillReturnError(fmt.Errorf("Some DB error"))
 
-	err = Run(dbConnectMock, []string{"some_table:id", "1-2"}, ".env.example", "sql", NewTestFileWriter(), "test_example.sql", "", ",")
+	err = Run(context.Background(), dbConnectMock, []string{"some_table:id", "1-2"}, testConnection, "sql", dumper.NewMemoryFileWriter(), "test_example.sql", "", nil, &DumpOptions{})
 	os.Setenv("DB_NAME", "")
 	if err == nil {
 		t.Errorf("Expected error, but got nil")
@@ -72,12 +85,44 @@ func TestRunDbError(t *testing.T) {
 	}
 }
 
+func TestRunValidationError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
+	}
+
+	expectSchemaQuery(mock, "some_table", "id", "bigint(20)")
+
+	fw := dumper.NewMemoryFileWriter()
+	err = Run(context.Background(), dbConnectMock, []string{"some_tabel:id,nmae", "1-2"}, testConnection, "sql", fw, "test_example.sql", "", nil, &DumpOptions{})
+	os.Setenv("DB_NAME", "")
+	if err == nil {
+		t.Fatalf("Expected validation error, but got nil")
+	}
+	assert.Equal(t, "Query does not match schema:\n  Table 'some_tabel' does not exist. Did you mean 'some_table'?", err.Error())
+	assert.Equal(t, 0, len(fw.Filenames()))
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	err = Run(context.Background(), dbConnectMock, []string{"some_tabel:id", "1-2"}, testConnection, "sql", fw, "test_example.sql", "", nil, &DumpOptions{skipValidation: true, dryRun: true})
+	os.Setenv("DB_NAME", "")
+	if err == nil {
+		t.Errorf("Expected DB error of DDL, but got nil")
+	}
+}
+
 func TestRunConfigReadError(t *testing.T) {
-	dbConnect := func(conset *ConnectionSettings) (db *sqlx.DB, err error) {
+	dbConnect := func(conset *ConnectionSettings) (db *sql.DB, err error) {
 		return nil, nil
 	}
 	os.Setenv("DB_NAME", "")
-	err := Run(dbConnect, []string{"some_table:id", "1-2"}, "not_existing_file", "", NewTestFileWriter(), "", "", ",")
+	err := Run(context.Background(), dbConnect, []string{"some_table:id", "1-2"}, &ConnectionOptions{configFile: "not_existing_file"}, "", dumper.NewMemoryFileWriter(), "", "", nil, &DumpOptions{})
 	os.Setenv("DB_NAME", "")
 	if err == nil {
 		t.Errorf("Expected error, but got nil")
@@ -86,11 +131,11 @@ func TestRunConfigReadError(t *testing.T) {
 }
 
 func TestRunParseError(t *testing.T) {
-	dbConnect := func(conset *ConnectionSettings) (db *sqlx.DB, err error) {
+	dbConnect := func(conset *ConnectionSettings) (db *sql.DB, err error) {
 		return nil, nil
 	}
 	os.Setenv("DB_NAME", "")
-	err := Run(dbConnect, []string{"", "", ""}, ".env.example", "", NewTestFileWriter(), "", "", ",")
+	err := Run(context.Background(), dbConnect, []string{"", "", ""}, testConnection, "", dumper.NewMemoryFileWriter(), "", "", nil, &DumpOptions{})
 	os.Setenv("DB_NAME", "")
 	if err == nil {
 		t.Errorf("Expected error, but got nil")
@@ -98,136 +143,535 @@ func TestRunParseError(t *testing.T) {
 	}
 }
 
-func TestGetConnectionSettingsFileError(t *testing.T) {
+func TestRunFormatError(t *testing.T) {
+	dbConnect := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return nil, fmt.Errorf("Connection is not expected")
+	}
+	err := Run(context.Background(), dbConnect, []string{"some_table:id", "1-2"}, testConnection, "xml", dumper.NewMemoryFileWriter(), "", "", nil, &DumpOptions{})
+	os.Setenv("DB_NAME", "")
+	assert.EqualError(t, err, "Unknown format 'xml'. Available formats: csv, simple, sql")
+
+	err = Run(context.Background(), dbConnect, []string{"some_table:id", "1-2"}, testConnection, "sql", dumper.NewMemoryFileWriter(), "", "", map[string]string{"csv-delimiter": ";"}, &DumpOptions{})
+	os.Setenv("DB_NAME", "")
+	assert.EqualError(t, err, "Flag --csv-delimiter is not supported by format 'sql'")
+}
+
+func TestRunGraph(t *testing.T) {
+	dbConnect := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return nil, fmt.Errorf("Connection is not expected")
+	}
+	args := []string{"routes:id;stations_for_routes:route_id;logs:id", "1-2", "routes.id=stations_for_routes.route_id"}
+	out := &bytes.Buffer{}
+	err := RunGraph(context.Background(), dbConnect, args, testConnection, "mermaid", false, out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Contains(t, out.String(), "routes }o--o{ stations_for_routes : \"id = route_id\"\n")
+	assert.Contains(t, out.String(), "style logs stroke:red,stroke-width:2px\n")
+
+	err = RunGraph(context.Background(), dbConnect, args, testConnection, "svg", false, out)
+	assert.EqualError(t, err, "Unknown graph format 'svg'. Available formats: dot, mermaid")
+
+	err = RunGraph(context.Background(), dbConnect, args, testConnection, "dot", true, out)
+	os.Setenv("DB_NAME", "")
+	assert.EqualError(t, err, "Connection is not expected")
+
+	err = RunGraph(context.Background(), dbConnect, []string{}, testConnection, "dot", false, out)
+	if err != nil {
+		t.Errorf("Expected help, but got error: %s", err)
+	}
+}
+
+func TestRunGraphAllRelations(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`KEY_COLUMN_USAGE` (.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "table_name", "column_name", "referenced_table_name", "referenced_column_name"}).
+			AddRow("fk_route", "stations_for_routes", "route_id", "routes", "id"))
+
+	out := &bytes.Buffer{}
+	err = RunGraph(context.Background(), dbConnectMock, []string{"routes:id;stations_for_routes:route_id", "1-2"}, testConnection, "dot", true, out)
+	os.Setenv("DB_NAME", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Contains(t, out.String(), "\"routes\" -- \"stations_for_routes\" [label=\"id = route_id\", style=dashed, color=gray, fontcolor=gray];\n")
+	assert.Contains(t, out.String(), "\"stations_for_routes\" [label=\"{stations_for_routes|route_id\\l}\", color=red, fontcolor=red];\n")
+}
+
+func TestRunDescribe(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
+	}
+
+	mock.ExpectQuery("DESCRIBE `routes`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`KEY_COLUMN_USAGE` (.+)").
+		WithArgs("routes", "routes").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "table_name", "column_name", "referenced_table_name", "referenced_column_name"}).
+			AddRow("fk_route", "stations_for_routes", "route_id", "routes", "id"))
+
+	out := &bytes.Buffer{}
+	err = RunDescribe(context.Background(), dbConnectMock, []string{"routes"}, testConnection, out)
+	os.Setenv("DB_NAME", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Contains(t, out.String(), "id      bigint(20)  NO    PRI")
+	assert.Contains(t, out.String(), "Referenced by:\n  id <- stations_for_routes.route_id (fk_route)\n")
+
+	err = RunDescribe(context.Background(), dbConnectMock, []string{}, testConnection, out)
+	if err != nil {
+		t.Errorf("Expected help, but got error: %s", err)
+	}
+}
+
+func TestRunTablesAndRelations(t *testing.T) {
+	mockDB, mock, err := sqlmock.NewWithDSN(t.Name())
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	// Every run closes its connection, so new connection to the same mock is opened
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return sql.Open("sqlmock", t.Name())
+	}
+
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`TABLES` (.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "rows"}).AddRow("routes", int64(3)))
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`KEY_COLUMN_USAGE` (.+)").
+		WithArgs("stations_for_routes", "stations_for_routes").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "table_name", "column_name", "referenced_table_name", "referenced_column_name"}).
+			AddRow("fk_route", "stations_for_routes", "route_id", "routes", "id"))
+
+	out := &bytes.Buffer{}
+	err = RunTables(context.Background(), dbConnectMock, []string{}, testConnection, out)
+	os.Setenv("DB_NAME", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "TABLE   EST. ROWS\nroutes  3\n", out.String())
+
+	out.Reset()
+	err = RunRelations(context.Background(), dbConnectMock, []string{"stations_for_routes"}, testConnection, out)
+	os.Setenv("DB_NAME", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Contains(t, out.String(), "routes.id=stations_for_routes.route_id  fk_route\n")
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	err = RunTables(context.Background(), dbConnectMock, []string{"extra"}, testConnection, out)
+	if err != nil {
+		t.Errorf("Expected help, but got error: %s", err)
+	}
+	err = RunRelations(context.Background(), dbConnectMock, []string{"a", "b"}, testConnection, out)
+	if err != nil {
+		t.Errorf("Expected help, but got error: %s", err)
+	}
+}
+
+func TestRunRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restore")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	dumpFile := filepath.Join(dir, "result.sql")
+	ioutil.WriteFile(dumpFile, []byte("SET FOREIGN_KEY_CHECKS=0;\nINSERT INTO `routes` (`id`) VALUES (1);\n"), 0644)
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
+	}
+
+	mock.ExpectExec("SET FOREIGN_KEY_CHECKS=0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `routes` (.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	out := &bytes.Buffer{}
+	err = RunRestore(context.Background(), dbConnectMock, []string{dumpFile}, testConnection, out)
+	os.Setenv("DB_NAME", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "Restored 2 statements from "+dumpFile+"\n", out.String())
+
+	err = RunRestore(context.Background(), dbConnectMock, []string{filepath.Join(dir, "not_existing.sql")}, testConnection, out)
 	os.Setenv("DB_NAME", "")
-	_, err := getConnectionSettings("not_existing_file")
 	if err == nil {
 		t.Errorf("Expected error, but got nil")
-		return
+	}
+
+	err = RunRestore(context.Background(), dbConnectMock, []string{}, testConnection, out)
+	if err != nil {
+		t.Errorf("Expected help, but got error: %s", err)
 	}
 }
 
-func TestGetWriterAndCombinedMode_sqlDefault(t *testing.T) {
-	format := "sql"
-	fw := NewTestFileWriter()
-	dstFile := ""
-	dstDir := ""
-	csvDelimiter := ","
-	writer, combined := getWriterAndCombinedMode(format, fw, dstFile, dstDir, csvDelimiter)
-	assert.IsType(t, &SqlWriter{}, writer)
-	sqlWriter := writer.(*SqlWriter)
-	assert.Equal(t, fw, sqlWriter.fw)
-	assert.Equal(t, "result.sql", sqlWriter.dstFile)
-	assert.Equal(t, "", sqlWriter.dstDir)
-	assert.False(t, combined)
-}
-
-func TestGetWriterAndCombinedMode_sqlDstFile(t *testing.T) {
-	format := "sql"
-	fw := NewTestFileWriter()
-	dstFile := "custom.sql"
-	dstDir := ""
-	csvDelimiter := ","
-	writer, combined := getWriterAndCombinedMode(format, fw, dstFile, dstDir, csvDelimiter)
-	assert.IsType(t, &SqlWriter{}, writer)
-	sqlWriter := writer.(*SqlWriter)
-	assert.Equal(t, "custom.sql", sqlWriter.dstFile)
-	assert.Equal(t, "", sqlWriter.dstDir)
-	assert.False(t, combined)
-}
-
-func TestGetWriterAndCombinedMode_sqlDstDir(t *testing.T) {
-	format := "sql"
-	fw := NewTestFileWriter()
-	dstFile := ""
-	dstDir := "./"
-	csvDelimiter := ","
-	writer, combined := getWriterAndCombinedMode(format, fw, dstFile, dstDir, csvDelimiter)
-	assert.IsType(t, &SqlWriter{}, writer)
-	sqlWriter := writer.(*SqlWriter)
-	assert.Equal(t, "", sqlWriter.dstFile)
-	assert.Equal(t, "./", sqlWriter.dstDir)
-	assert.False(t, combined)
-}
-
-func TestGetWriterAndCombinedMode_csvDefault(t *testing.T) {
-	format := "csv"
-	fw := NewTestFileWriter()
-	dstFile := ""
-	dstDir := ""
-	csvDelimiter := ","
-	writer, combined := getWriterAndCombinedMode(format, fw, dstFile, dstDir, csvDelimiter)
-	assert.IsType(t, &CsvWriter{}, writer)
-	csvWriter := writer.(*CsvWriter)
-	assert.Equal(t, fw, csvWriter.fw)
-	assert.Equal(t, "result.csv", csvWriter.dstFile)
-	assert.Equal(t, "", csvWriter.dstDir)
-	assert.True(t, combined)
-}
-
-func TestGetWriterAndCombinedMode_csvDstFile(t *testing.T) {
-	format := "csv"
-	fw := NewTestFileWriter()
-	dstFile := "custom.csv"
-	dstDir := ""
-	csvDelimiter := ","
-	writer, combined := getWriterAndCombinedMode(format, fw, dstFile, dstDir, csvDelimiter)
-	assert.IsType(t, &CsvWriter{}, writer)
-	csvWriter := writer.(*CsvWriter)
-	assert.Equal(t, fw, csvWriter.fw)
-	assert.Equal(t, "custom.csv", csvWriter.dstFile)
-	assert.Equal(t, "", csvWriter.dstDir)
-	assert.True(t, combined)
-}
-
-func TestGetWriterAndCombinedMode_csvDstDir(t *testing.T) {
-	format := "csv"
-	fw := NewTestFileWriter()
-	dstFile := ""
-	dstDir := "./"
-	csvDelimiter := ","
-	writer, combined := getWriterAndCombinedMode(format, fw, dstFile, dstDir, csvDelimiter)
-	assert.IsType(t, &CsvWriter{}, writer)
-	csvWriter := writer.(*CsvWriter)
-	assert.Equal(t, fw, csvWriter.fw)
-	assert.Equal(t, "", csvWriter.dstFile)
-	assert.Equal(t, "./", csvWriter.dstDir)
-	assert.False(t, combined)
-}
-
-func TestGetWriterAndCombinedMode_simple(t *testing.T) {
-	format := "simple"
-	fw := NewTestFileWriter()
-	dstFile := ""
-	dstDir := ""
-	csvDelimiter := ","
-	writer, combined := getWriterAndCombinedMode(format, fw, dstFile, dstDir, csvDelimiter)
-	assert.IsType(t, &SimpleWriter{}, writer)
-	assert.True(t, combined)
-}
-
-func TestGetConnectionSettings(t *testing.T) {
-	os.Setenv("DB_NAME", "")
-	conset, err := getConnectionSettings(".env.example")
-	os.Setenv("DB_NAME", "")
+func TestRestoreCompressedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restore")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	gzipFile := filepath.Join(dir, "result.sql.gz")
+	f, _ := os.Create(gzipFile)
+	gzipWriter := gzip.NewWriter(f)
+	gzipWriter.Write([]byte("INSERT INTO `routes` (`id`) VALUES (1);\n"))
+	gzipWriter.Close()
+	f.Close()
+	brokenFile := filepath.Join(dir, "broken.sql.zst")
+	ioutil.WriteFile(brokenFile, []byte("INSERT INTO `routes` (`id`) VALUES (2);\n"), 0644)
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectExec("INSERT INTO `routes` (.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	statements, err := restoreFile(context.Background(), mockDB, gzipFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, 1, statements)
+
+	_, err = restoreFile(context.Background(), mockDB, brokenFile)
+	if err == nil {
+		t.Errorf("Expected error of decompression, but got nil")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCommandsHelp(t *testing.T) {
+	for name := range commands {
+		if _, ok := commandsHelp[name]; !ok {
+			t.Errorf("Expected help for command %s", name)
+		}
+	}
+	assert.Equal(t, len(commands), len(commandsHelp))
+}
+
+func TestGetMaskingRules(t *testing.T) {
+	rules, err := getMaskingRules("users.email=null", "", "")
 	if err != nil {
 		t.Errorf("Unexpected error: %s", err)
 		return
 	}
-	fields := map[string]string{
-		"driver":   "mysql",
-		"user":     "root",
-		"password": "root",
-		"dbname":   "test",
-		"dbhost":   "127.0.0.1",
-	}
-	for key, val := range fields {
-		r := reflect.ValueOf(conset)
-		f := reflect.Indirect(r).FieldByName(key)
-		actualValue := f.String()
-		if actualValue != val {
-			t.Errorf("Expected for %s: %s, got: %s", key, val, actualValue)
-			return
-		}
+	if len(rules) != 1 {
+		t.Errorf("Expected 1 rule, got %d", len(rules))
+	}
+
+	_, err = getMaskingRules("users.email=unknown", "", "")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+
+	_, err = getMaskingRules("", "not_existing_file", "")
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+
+	_, err = getMaskingRules("users.id=pseudo", "", "")
+	if err == nil {
+		t.Errorf("Expected error about secret, but got nil")
+	}
+
+	_, err = getMaskingRules("users.id=pseudo", "", "secret")
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestRunWithCheckpointAndResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	outputFile := filepath.Join(dir, "result.sql")
+	checkpointFile := filepath.Join(dir, "checkpoint.json")
+	args := []string{"some_table:id", "1-2"}
+
+	mockDB, mock, err := sqlmock.NewWithDSN(t.Name())
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	// Every run closes its connection, so new connection to the same mock is opened
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return sql.Open("sqlmock", t.Name())
+	}
+
+	expectSchemaQuery(mock, "some_table", "id", "bigint(20)")
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(2, 2).
+		WillReturnError(fmt.Errorf("Some DB error"))
+
+	options := &DumpOptions{chunkSize: 1, checkpointFile: checkpointFile}
+	err = Run(context.Background(), dbConnectMock, args, testConnection, "sql", dumper.NewOsFileWriter(), outputFile, "", nil, options)
+	if err == nil {
+		t.Fatalf("Expected error, but got nil")
+	}
+	if _, err = os.Stat(outputFile); err != nil {
+		t.Fatalf("Expected kept output file, but got %s", err)
+	}
+
+	expectSchemaQuery(mock, "some_table", "id", "bigint(20)")
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	// Written chunk is selected again only to remember keys of its rows
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+	err = RunResume(context.Background(), dbConnectMock, checkpointFile, []string{}, testConnection, dumper.NewOsFileWriter(), &DumpOptions{})
+	os.Setenv("DB_NAME", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+
+	contents, _ := ioutil.ReadFile(outputFile)
+	expected := "SET FOREIGN_KEY_CHECKS=0;\n" +
+		"CREATE TABLE `some_table` (\n" +
+		"    `id` bigint(20) NOT NULL,\n" +
+		"    PRIMARY KEY (`id`)\n" +
+		");\n" +
+		"SET FOREIGN_KEY_CHECKS=1;\n" +
+		"INSERT INTO `some_table` (`id`) VALUES (1);\n" +
+		"INSERT INTO `some_table` (`id`) VALUES (2);\n"
+	assert.Equal(t, expected, string(contents))
+	if _, err = os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Errorf("Expected removed checkpoint file, but got %v", err)
+	}
+}
+
+func TestRunResumeArgumentsError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	checkpointFile := filepath.Join(dir, "checkpoint.json")
+	ioutil.WriteFile(checkpointFile, []byte(`{"args":["some_table:id","1-2"],"format":"sql"}`), 0644)
+
+	err = RunResume(context.Background(), nil, checkpointFile, []string{"other_table:id", "1-2"}, testConnection, dumper.NewOsFileWriter(), &DumpOptions{})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestRunEstimate(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
+	}
+
+	mock.ExpectQuery("EXPLAIN SELECT (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "select_type", "table", "type", "possible_keys", "key", "rows", "Extra"}).
+			AddRow(1, "SIMPLE", "some_table", "ALL", nil, nil, int64(10), ""))
+
+	out := &bytes.Buffer{}
+	err = RunEstimate(context.Background(), dbConnectMock, []string{"some_table:id", "1-2"}, testConnection, &dumper.EstimateOptions{MaxFullScans: 0, MaxRows: 5}, out)
+	os.Setenv("DB_NAME", "")
+	if err == nil {
+		t.Errorf("Expected error about rows, but got nil")
+	}
+	if !strings.Contains(out.String(), "full scan of some_table") {
+		t.Errorf("Expected printed estimates, got: %s", out.String())
+	}
+
+	err = RunEstimate(context.Background(), dbConnectMock, []string{}, testConnection, &dumper.EstimateOptions{}, out)
+	if err != nil {
+		t.Errorf("Expected help, but got error: %s", err)
+	}
+}
+
+// expectSchemaQuery expects reading of columns for validation of query. Arguments are triples of table, column and type.
+func expectSchemaQuery(mock sqlmock.Sqlmock, columns ...string) {
+	rows := sqlmock.NewRows([]string{"table_name", "column_name", "column_type"})
+	for i := 0; i+2 < len(columns); i += 3 {
+		rows.AddRow(columns[i], columns[i+1], columns[i+2])
+	}
+	mock.ExpectQuery("SELECT (.+) FROM `information_schema`.`COLUMNS` (.+)").WillReturnRows(rows)
+}
+
+func TestRunCompressed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compressed")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	args := []string{"some_table:id", "1-2"}
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
+	}
+
+	expectSchemaQuery(mock, "some_table", "id", "bigint(20)")
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	compression, err := getCompression("", filepath.Join(dir, "result.sql.gz"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = Run(context.Background(), dbConnectMock, args, testConnection, "sql", dumper.NewOsFileWriter(dumper.WithCompression(compression)), filepath.Join(dir, "result.sql"), "", nil, &DumpOptions{jobs: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	file, err := os.Open(filepath.Join(dir, "result.sql.gz"))
+	if err != nil {
+		t.Fatalf("Expected compressed output file, but got %s", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents, _ := ioutil.ReadAll(reader)
+	assert.Contains(t, string(contents), "INSERT INTO `some_table` (`id`) VALUES (2);\n")
+
+	err = Run(context.Background(), dbConnectMock, args, testConnection, "sql", dumper.NewOsFileWriter(dumper.WithCompression(compression)), filepath.Join(dir, "other.sql"), "", nil, &DumpOptions{jobs: 1, checkpointFile: filepath.Join(dir, "checkpoint.json"), skipValidation: true})
+	if err == nil || !strings.Contains(err.Error(), "Checkpoints are not available for compressed output") {
+		t.Errorf("Expected error about checkpoint of compressed output, but got %v", err)
+	}
+}
+
+func TestGetCompression(t *testing.T) {
+	compression, err := getCompression("zstd", "result.sql.gz")
+	assert.Nil(t, err)
+	assert.Equal(t, "zstd", compression.Name)
+
+	compression, err = getCompression("", "result.sql.gz")
+	assert.Nil(t, err)
+	assert.Equal(t, "gzip", compression.Name)
+
+	compression, err = getCompression("", "result.sql")
+	assert.Nil(t, err)
+	assert.Nil(t, compression)
+
+	_, err = getCompression("rar", "")
+	if err == nil {
+		t.Errorf("Expected error of unknown compression, but got nil")
+	}
+}
+
+func TestGetWriteMode(t *testing.T) {
+	mode, err := getWriteMode(false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, dumper.WriteCreate, mode)
+
+	mode, err = getWriteMode(true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, dumper.WriteOverwrite, mode)
+
+	mode, err = getWriteMode(false, true)
+	assert.Nil(t, err)
+	assert.Equal(t, dumper.WriteAppend, mode)
+
+	_, err = getWriteMode(true, true)
+	assert.EqualError(t, err, "Flags --overwrite and --append can not be used together")
+}
+
+func TestRunIntoStdout(t *testing.T) {
+	stdout, err := ioutil.TempFile("", "stdout")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.Remove(stdout.Name())
+	defer stdout.Close()
+	originalStdout := os.Stdout
+	os.Stdout = stdout
+	fw := dumper.NewOsFileWriter()
+	os.Stdout = originalStdout
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	dbConnectMock := func(conset *ConnectionSettings) (db *sql.DB, err error) {
+		return mockDB, nil
+	}
+
+	expectSchemaQuery(mock, "some_table", "id", "bigint(20)")
+	mock.ExpectQuery("DESCRIBE `some_table`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"Field", "Type", "Null", "Key", "Default", "Extra"}).AddRow("id", "bigint(20)", "NO", "PRI", nil, ""),
+		)
+	mock.ExpectQuery("SELECT (.+) FROM `some_table` (.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	err = Run(context.Background(), dbConnectMock, []string{"some_table:id", "1-2"}, testConnection, "sql", fw, dumper.Stdout, "", nil, &DumpOptions{jobs: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	contents, _ := ioutil.ReadFile(stdout.Name())
+	assert.True(t, strings.HasPrefix(string(contents), "SET FOREIGN_KEY_CHECKS=0;\n"))
+	assert.Contains(t, string(contents), "INSERT INTO `some_table` (`id`) VALUES (2);\n")
+	if _, err = os.Stat(dumper.Stdout); !os.IsNotExist(err) {
+		t.Errorf("Expected no file with name of stdout, but got %v", err)
 	}
 }
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultSSHPort is port of SSH server when it is not defined in destination
+const defaultSSHPort = "22"
+
+// defaultSSHKeys are private keys in ~/.ssh which are used when --ssh-key is not defined
+var defaultSSHKeys = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// sshTunnel forwards connections from local port to DB through SSH server
+type sshTunnel struct {
+	client     *ssh.Client
+	listener   net.Listener
+	remoteNet  string
+	remoteAddr string
+	wg         sync.WaitGroup
+}
+
+// openSSHTunnel connects to SSH server from settings and listens local port which is forwarded to remote address.
+// Host key of server is verified by known_hosts file.
+func (conset *ConnectionSettings) openSSHTunnel(remoteNet string, remoteAddr string) (*sshTunnel, error) {
+	sshUser, sshAddr, err := parseSSHDestination(conset.ssh)
+	if err != nil {
+		return nil, err
+	}
+	hostKeyCallback, err := conset.sshHostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := conset.sshAuthMethods()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ssh.Dial("tcp", sshAddr, &ssh.ClientConfig{
+		User:            sshUser,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Fail to connect to SSH server %s: %s", sshAddr, err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("Fail to listen local port of SSH tunnel: %s", err)
+	}
+
+	tunnel := &sshTunnel{
+		client:     client,
+		listener:   listener,
+		remoteNet:  remoteNet,
+		remoteAddr: remoteAddr,
+	}
+	tunnel.wg.Add(1)
+	go tunnel.serve()
+	logger.Info("SSH tunnel is opened", "ssh", sshAddr, "local", tunnel.localAddr(), "remote", remoteAddr)
+	return tunnel, nil
+}
+
+// localAddr returns address of local port which is forwarded to DB
+func (tunnel *sshTunnel) localAddr() string {
+	return tunnel.listener.Addr().String()
+}
+
+// Close stops listening local port and closes connection to SSH server
+func (tunnel *sshTunnel) Close() error {
+	err := tunnel.listener.Close()
+	tunnel.wg.Wait()
+	if clientErr := tunnel.client.Close(); err == nil {
+		err = clientErr
+	}
+	return err
+}
+
+func (tunnel *sshTunnel) serve() {
+	defer tunnel.wg.Done()
+	for {
+		local, err := tunnel.listener.Accept()
+		if err != nil {
+			return
+		}
+		go tunnel.forward(local)
+	}
+}
+
+func (tunnel *sshTunnel) forward(local net.Conn) {
+	defer local.Close()
+	remote, err := tunnel.client.Dial(tunnel.remoteNet, tunnel.remoteAddr)
+	if err != nil {
+		logger.Error("Fail to open connection through SSH tunnel", "remote", tunnel.remoteAddr, "error", err)
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// parseSSHDestination returns user and address of SSH server from destination in format [user@]host[:port]
+func parseSSHDestination(destination string) (sshUser string, addr string, err error) {
+	host := destination
+	if i := strings.LastIndex(destination, "@"); i >= 0 {
+		sshUser = destination[:i]
+		host = destination[i+1:]
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("Empty host of SSH server in '%s'", destination)
+	}
+	if sshUser == "" {
+		current, err := user.Current()
+		if err != nil {
+			return "", "", fmt.Errorf("Fail to get user for SSH: %s", err)
+		}
+		sshUser = current.Username
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), defaultSSHPort)
+	}
+	return sshUser, host, nil
+}
+
+// sshHostKeyCallback verifies host key of SSH server by DB_SSH_KNOWN_HOSTS or ~/.ssh/known_hosts
+func (conset *ConnectionSettings) sshHostKeyCallback() (ssh.HostKeyCallback, error) {
+	knownHostsFile := conset.sshKnownHosts
+	if knownHostsFile == "" {
+		knownHostsFile = "~/.ssh/known_hosts"
+	}
+	callback, err := knownhosts.New(expandHomeDir(knownHostsFile))
+	if err != nil {
+		return nil, fmt.Errorf("Fail to read known hosts of SSH: %s", err)
+	}
+	return callback, nil
+}
+
+// sshAuthMethods returns authentication by private key from DB_SSH_KEY or default keys of ~/.ssh and by SSH agent
+func (conset *ConnectionSettings) sshAuthMethods() ([]ssh.AuthMethod, error) {
+	signers := make([]ssh.Signer, 0)
+	if conset.sshKey != "" {
+		signer, err := readSSHKey(expandHomeDir(conset.sshKey))
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	} else {
+		for _, name := range defaultSSHKeys {
+			signer, err := readSSHKey(expandHomeDir(filepath.Join("~/.ssh", name)))
+			if err == nil {
+				signers = append(signers, signer)
+			}
+		}
+	}
+
+	auth := make([]ssh.AuthMethod, 0)
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("No private keys or SSH agent for SSH tunnel")
+	}
+	return auth, nil
+}
+
+// readSSHKey reads private key, passphrase of encrypted key is asked in terminal
+func readSSHKey(keyFile string) (ssh.Signer, error) {
+	pem, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to read SSH key: %s", err)
+	}
+	signer, err := ssh.ParsePrivateKey(pem)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok {
+		passphrase, promptErr := readPassword(fmt.Sprintf("Passphrase for %s: ", keyFile))
+		if promptErr != nil {
+			return nil, fmt.Errorf("Fail to read passphrase of SSH key: %s", promptErr)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Fail to parse SSH key %s: %s", keyFile, err)
+	}
+	return signer, nil
+}
+
+// expandHomeDir replaces ~ at the beginning of path by home directory of user
+func expandHomeDir(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := userHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
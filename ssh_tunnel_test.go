@@ -0,0 +1,303 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// testSSHServer is in-process SSH server which forwards direct-tcpip channels
+type testSSHServer struct {
+	listener  net.Listener
+	hostKey   ssh.Signer
+	forwarded []string
+}
+
+func startTestSSHServer(t *testing.T, authorizedKey ssh.PublicKey) *testSSHServer {
+	_, hostPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	hostKey, err := ssh.NewSignerFromKey(hostPrivateKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "tunnel" && string(key.Marshal()) == string(authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key of %s", conn.User())
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	server := &testSSHServer{listener: listener, hostKey: hostKey}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn, config)
+		}
+	}()
+	return server
+}
+
+func (server *testSSHServer) handle(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip is supported")
+			continue
+		}
+		var target struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		ssh.Unmarshal(newChannel.ExtraData(), &target)
+		addr := net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port)))
+		server.forwarded = append(server.forwarded, addr)
+		remote, err := net.Dial("tcp", addr)
+		if err != nil {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			remote.Close()
+			continue
+		}
+		go ssh.DiscardRequests(channelRequests)
+		go func() {
+			io.Copy(channel, remote)
+			channel.Close()
+		}()
+		go func() {
+			io.Copy(remote, channel)
+			remote.Close()
+		}()
+	}
+}
+
+// startEchoServer starts TCP server which plays role of DB and returns received data back
+func startEchoServer(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return listener
+}
+
+// writeTestSSHKey writes private key of client and returns its public key
+func writeTestSSHKey(t *testing.T, dir string) (keyFile string, publicKey ssh.PublicKey) {
+	_, clientPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(clientPrivateKey, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	keyFile = filepath.Join(dir, "id_ed25519")
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(block), 0600)
+	signer, _ := ssh.NewSignerFromKey(clientPrivateKey)
+	return keyFile, signer.PublicKey()
+}
+
+// writeTestKnownHosts writes known_hosts with host key of server
+func writeTestKnownHosts(dir string, server *testSSHServer) string {
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(server.listener.Addr().String())}, server.hostKey.PublicKey())
+	ioutil.WriteFile(knownHostsFile, []byte(line+"\n"), 0600)
+	return knownHostsFile
+}
+
+func TestSSHTunnel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("SSH_AUTH_SOCK", "")
+
+	keyFile, publicKey := writeTestSSHKey(t, dir)
+	server := startTestSSHServer(t, publicKey)
+	defer server.listener.Close()
+	knownHostsFile := writeTestKnownHosts(dir, server)
+	echo := startEchoServer(t)
+	defer echo.Close()
+
+	conset := &ConnectionSettings{
+		driver:        "mysql",
+		ssh:           "tunnel@" + server.listener.Addr().String(),
+		sshKey:        keyFile,
+		sshKnownHosts: knownHostsFile,
+	}
+	tunnel, err := conset.openSSHTunnel("tcp", echo.Addr().String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	conn, err := net.Dial("tcp", tunnel.localAddr())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	conn.Write([]byte("ping"))
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	conn.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	assert.Equal(t, "ping", string(reply))
+	assert.Equal(t, []string{echo.Addr().String()}, server.forwarded)
+
+	tunnel.Close()
+	_, err = net.Dial("tcp", tunnel.localAddr())
+	if err == nil {
+		t.Errorf("Expected error of closed tunnel, but got nil")
+	}
+}
+
+func TestSSHTunnelErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("SSH_AUTH_SOCK", "")
+
+	keyFile, publicKey := writeTestSSHKey(t, dir)
+	server := startTestSSHServer(t, publicKey)
+	defer server.listener.Close()
+	knownHostsFile := writeTestKnownHosts(dir, server)
+	addr := server.listener.Addr().String()
+
+	conset := &ConnectionSettings{driver: "mysql", ssh: "other@" + addr, sshKey: keyFile, sshKnownHosts: knownHostsFile}
+	_, err = conset.openSSHTunnel("tcp", "db:3306")
+	if err == nil {
+		t.Errorf("Expected error of unauthorized user, but got nil")
+	}
+
+	otherServer := startTestSSHServer(t, publicKey)
+	defer otherServer.listener.Close()
+	conset = &ConnectionSettings{driver: "mysql", ssh: "tunnel@" + otherServer.listener.Addr().String(), sshKey: keyFile, sshKnownHosts: knownHostsFile}
+	_, err = conset.openSSHTunnel("tcp", "db:3306")
+	if err == nil {
+		t.Errorf("Expected error of unknown host key, but got nil")
+	}
+
+	conset = &ConnectionSettings{driver: "mysql", ssh: "tunnel@" + addr, sshKey: filepath.Join(dir, "not_existing"), sshKnownHosts: knownHostsFile}
+	_, err = conset.openSSHTunnel("tcp", "db:3306")
+	if err == nil {
+		t.Errorf("Expected error of missing key, but got nil")
+	}
+}
+
+func TestNewConnectorWithSSHTunnel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("SSH_AUTH_SOCK", "")
+
+	keyFile, publicKey := writeTestSSHKey(t, dir)
+	server := startTestSSHServer(t, publicKey)
+	defer server.listener.Close()
+	knownHostsFile := writeTestKnownHosts(dir, server)
+
+	conset := &ConnectionSettings{
+		driver:        "mysql",
+		user:          "user",
+		dbname:        "shop",
+		dbhost:        "db.internal",
+		ssh:           "tunnel@" + server.listener.Addr().String(),
+		sshKey:        keyFile,
+		sshKnownHosts: knownHostsFile,
+	}
+	connector, err := conset.newConnector()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	closer, ok := connector.(io.Closer)
+	if !ok {
+		t.Fatalf("Expected connector which closes SSH tunnel")
+	}
+	localAddr := connector.(*tunnelConnector).tunnel.localAddr()
+	assert.Equal(t, "db.internal:3306", connector.(*tunnelConnector).tunnel.remoteAddr)
+	closer.Close()
+	_, err = net.Dial("tcp", localAddr)
+	if err == nil {
+		t.Errorf("Expected error of closed tunnel, but got nil")
+	}
+}
+
+func TestPinServerName(t *testing.T) {
+	cfg := mysql.NewConfig()
+	cfg.TLSConfig = "true"
+	pinServerName(cfg, "db.internal:3306")
+	assert.Equal(t, "db.internal", cfg.TLS.ServerName)
+
+	cfg = mysql.NewConfig()
+	pinServerName(cfg, "db.internal:3306")
+	if cfg.TLS != nil {
+		t.Errorf("Expected no TLS, but got %v", cfg.TLS)
+	}
+}
+
+func TestParseSSHDestination(t *testing.T) {
+	sshUser, addr, err := parseSSHDestination("deploy@bastion.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "deploy", sshUser)
+	assert.Equal(t, "bastion.example.com:22", addr)
+
+	sshUser, addr, err = parseSSHDestination("deploy@bastion:2222")
+	assert.Nil(t, err)
+	assert.Equal(t, "deploy", sshUser)
+	assert.Equal(t, "bastion:2222", addr)
+
+	_, addr, err = parseSSHDestination("[::1]")
+	assert.Nil(t, err)
+	assert.Equal(t, "[::1]:22", addr)
+
+	_, _, err = parseSSHDestination("deploy@")
+	assert.EqualError(t, err, "Empty host of SSH server in 'deploy@'")
+}
+
+func TestEnsurePort(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:3306", ensurePort(""))
+	assert.Equal(t, "db:3306", ensurePort("db"))
+	assert.Equal(t, "db:3307", ensurePort("db:3307"))
+	assert.Equal(t, "[::1]:3306", ensurePort("::1"))
+}
@@ -1,10 +0,0 @@
-package main
-
-func contains(haystack []string, needle string) bool {
-	for _, a := range haystack {
-		if a == needle {
-			return true
-		}
-	}
-	return false
-}